@@ -39,19 +39,493 @@ type VmSpec struct {
 	UserData           string   `json:"userData,omitempty"`
 	DryRun             bool     `json:"dryRun,omitempty"`
 	IamInstanceProfile string   `json:"iamInstanceProfile,omitempty"`
-	// NetworkInterface              []ec2.InstanceNetworkInterfaceSpecification `json:"NetworkInterface,omitempty"`
-	// BlockDeviceMapping            ec2.BlockDeviceMapping                      `json:"BlockDeviceMapping,omitempty"`
-	// MetadataOptions               ec2.InstanceMetadataOptionsRequest          `json:"MetadataOptions,omitempty"`
 	// PrivateDnsNameOptionsOnLaunch ec2.PrivateDnsNameOptionsOnLaunch           `json:"PrivateDnsNameOptionsOnLaunch,omitempty"`
+
+	// BlockDeviceMappings configures the root and additional EBS volumes created at launch.
+	BlockDeviceMappings []BlockDeviceMapping `json:"blockDeviceMappings,omitempty"`
+
+	// NetworkInterfaces attaches one or more network interfaces at launch. When set,
+	// SubnetId and SecurityGroupIds only act as defaults for the primary interface.
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
+
+	// MetadataOptions configures the instance metadata service. It is applied at
+	// launch and re-applied to running instances whenever they drift from it.
+	MetadataOptions *MetadataOptions `json:"metadataOptions,omitempty"`
+
+	// Tags are applied to the instances and to their volumes and network interfaces.
+	// Tags removed from this map are also removed from those resources.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// AdoptInstanceIds brings existing instances under this Vm instead of launching
+	// new ones. The instances are tagged as owned by the Vm on adoption.
+	AdoptInstanceIds []string `json:"adoptInstanceIds,omitempty"`
+
+	// ScaleDownPolicy selects which instances are terminated first when the
+	// desired count drops. Defaults to NewestFirst.
+	// +kubebuilder:validation:Enum=NewestFirst;OldestFirst;AvailabilityZone
+	ScaleDownPolicy string `json:"scaleDownPolicy,omitempty"`
+
+	// PowerState is the desired power state of the instances. Defaults to Running.
+	// +kubebuilder:validation:Enum=Running;Stopped;Hibernated
+	PowerState string `json:"powerState,omitempty"`
+
+	// Hibernation enables hibernation support at launch, which is required to
+	// use the Hibernated power state. The root volume must be encrypted and
+	// large enough to hold the instance memory.
+	Hibernation bool `json:"hibernation,omitempty"`
+
+	// UpdateStrategy controls how changes are rolled out to existing instances.
+	UpdateStrategy *UpdateStrategy `json:"updateStrategy,omitempty"`
+
+	// InstanceMarketOptions launches the instances as spot instances.
+	InstanceMarketOptions *InstanceMarketOptions `json:"instanceMarketOptions,omitempty"`
+
+	// Placement controls where the instances run.
+	Placement *Placement `json:"placement,omitempty"`
+
+	// SubnetIds spreads the instances round-robin across the given subnets,
+	// launching each instance into the subnet with the fewest instances. It
+	// takes precedence over SubnetId. Changing the list only affects where new
+	// instances are launched.
+	SubnetIds []string `json:"subnetIds,omitempty"`
+
+	// LaunchTemplate launches the instances from an EC2 launch template. The
+	// other spec fields that are set override the template.
+	LaunchTemplate *LaunchTemplateReference `json:"launchTemplate,omitempty"`
+
+	// ImageSelector looks up the AMI when ImageId is empty.
+	ImageSelector *ImageSelector `json:"imageSelector,omitempty"`
+
+	// SubnetSelector picks the subnet when neither SubnetId nor SubnetIds is
+	// set. It is resolved before each launch, so a different subnet only
+	// affects newly launched instances.
+	SubnetSelector *SubnetSelector `json:"subnetSelector,omitempty"`
+
+	// SecurityGroupSelector picks the security groups when SecurityGroupIds is
+	// empty. Every matching group is used.
+	SecurityGroupSelector *ResourceSelector `json:"securityGroupSelector,omitempty"`
+
+	// ElasticIp associates an Elastic IP address with every instance.
+	ElasticIp *ElasticIp `json:"elasticIp,omitempty"`
+
+	// CapacityFallback lists alternatives tried in order when AWS has no
+	// capacity for the instance type or subnet of the spec.
+	CapacityFallback *CapacityFallback `json:"capacityFallback,omitempty"`
+
+	// DeletionPolicy is what happens to the instances when the Vm is deleted:
+	// Delete terminates them, Stop stops them, and Retain leaves them running
+	// and removes the ownership tags so another Vm can adopt them. Defaults
+	// to Delete. A deleting Vm whose credentials Secret is gone waits for it
+	// to be restored, unless it carries the orphan-instances annotation.
+	// +kubebuilder:validation:Enum=Delete;Retain;Stop
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// DriftPolicy is how instances modified outside the controller are
+	// handled: Report only records the drift, Correct reverts the instance
+	// to the spec and Replace terminates it so a new one is launched.
+	// Defaults to Correct. Spec changes are always applied.
+	// +kubebuilder:validation:Enum=Report;Correct;Replace
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// HealthRemediation enables automatic repair of instances that fail their
+	// EC2 status checks or are scheduled for retirement. Instances are only
+	// reported as unhealthy when unset.
+	HealthRemediation *HealthRemediation `json:"healthRemediation,omitempty"`
+}
+
+// CapacityFallback lists the acceptable alternatives to the spec instance type
+// and subnet. Every instance type is tried in every subnet before moving on to
+// the next type, and combinations the zone does not offer are skipped upfront.
+type CapacityFallback struct {
+	// InstanceTypes are tried in order after spec.instanceType. Instances
+	// running one of them are not resized to spec.instanceType.
+	InstanceTypes []string `json:"instanceTypes,omitempty"`
+
+	// SubnetIds are tried in order after the subnet of the spec.
+	SubnetIds []string `json:"subnetIds,omitempty"`
+}
+
+// ElasticIp describes the Elastic IP addresses associated with the instances.
+type ElasticIp struct {
+	// AllocationIds are existing addresses handed out to the instances in
+	// order. They are never released by the controller.
+	AllocationIds []string `json:"allocationIds,omitempty"`
+
+	// Allocate allocates a new address for every instance left without one
+	// of AllocationIds. Allocated addresses are released when the Vm is deleted.
+	Allocate bool `json:"allocate,omitempty"`
+}
+
+// ResourceSelector matches EC2 resources by tags and VPC.
+type ResourceSelector struct {
+	// Tags the resource must carry. An empty value matches any value of the key.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// VpcId is the VPC the resource must belong to.
+	VpcId string `json:"vpcId,omitempty"`
+}
+
+// SubnetSelector matches subnets and picks the one instances are launched into.
+type SubnetSelector struct {
+	ResourceSelector `json:",inline"`
+
+	// AvailabilityZone prefers the matching subnets in this zone. Among the
+	// candidates the subnet with the most free IP addresses wins.
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+}
+
+// ImageSelector finds an AMI either through an SSM parameter holding its ID or
+// by searching the images matching the filters, the newest one winning.
+type ImageSelector struct {
+	// SsmParameter is the path of an SSM parameter whose value is an AMI ID,
+	// such as /aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64.
+	// When set, the filters are ignored.
+	SsmParameter string `json:"ssmParameter,omitempty"`
+
+	// Name is an image name pattern that may contain * and ? wildcards.
+	Name string `json:"name,omitempty"`
+
+	// Owners are the account IDs or aliases, such as amazon or self, that own the image.
+	Owners []string `json:"owners,omitempty"`
+
+	// Architecture is the image architecture, such as x86_64 or arm64.
+	Architecture string `json:"architecture,omitempty"`
+
+	// UpdatePolicy is Pinned to keep launching the AMI resolved first, or
+	// Rollout to follow the selector and replace the instances whenever it
+	// resolves to a new AMI. Defaults to Pinned.
+	// +kubebuilder:validation:Enum=Pinned;Rollout
+	UpdatePolicy string `json:"updatePolicy,omitempty"`
+}
+
+const (
+	// ImageUpdatePinned keeps the resolved AMI until the selector itself changes.
+	ImageUpdatePinned = "Pinned"
+	// ImageUpdateRollout re-resolves the AMI on every reconcile and rolls the
+	// instances onto a new one.
+	ImageUpdateRollout = "Rollout"
+)
+
+// LaunchTemplateReference identifies an EC2 launch template version by ID or
+// by name.
+type LaunchTemplateReference struct {
+	// LaunchTemplateId is the ID of the template.
+	LaunchTemplateId string `json:"launchTemplateId,omitempty"`
+
+	// LaunchTemplateName is the name of the template, used when no ID is given.
+	LaunchTemplateName string `json:"launchTemplateName,omitempty"`
+
+	// Version is Latest, Default or a version number. It is resolved to a
+	// version number when instances are launched; instances are not replaced
+	// when the Latest or Default version moves. Defaults to Default.
+	// +kubebuilder:validation:Pattern=`^(Latest|Default|[0-9]+)$`
+	Version string `json:"version,omitempty"`
+}
+
+// Placement describes where the instances are launched.
+type Placement struct {
+	// AvailabilityZone pins the instances to a zone. It must match the subnet.
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+
+	// Tenancy is default for shared hardware, dedicated for single-tenant
+	// hardware or host for a dedicated host.
+	// +kubebuilder:validation:Enum=default;dedicated;host
+	Tenancy string `json:"tenancy,omitempty"`
+
+	// GroupName is the placement group the instances join.
+	GroupName string `json:"groupName,omitempty"`
+
+	// PartitionNumber is the partition of a partition placement group.
+	// +kubebuilder:validation:Minimum=1
+	PartitionNumber int64 `json:"partitionNumber,omitempty"`
+
+	// HostId is the dedicated host to launch on, for host tenancy.
+	HostId string `json:"hostId,omitempty"`
+}
+
+// InstanceMarketOptions describes the market the instances are purchased in.
+type InstanceMarketOptions struct {
+	// MarketType is the purchasing market. Only spot is supported.
+	// +kubebuilder:validation:Enum=spot
+	// +kubebuilder:default=spot
+	MarketType string `json:"marketType,omitempty"`
+
+	// SpotOptions configures the spot request.
+	SpotOptions *SpotOptions `json:"spotOptions,omitempty"`
+
+	// FallbackToOnDemand launches on-demand instances when spot capacity is not
+	// available, including when interrupted spot instances are relaunched.
+	FallbackToOnDemand bool `json:"fallbackToOnDemand,omitempty"`
+}
+
+// SpotOptions configures the spot request of the instances.
+type SpotOptions struct {
+	// MaxPrice is the maximum hourly price, defaulting to the on-demand price.
+	MaxPrice string `json:"maxPrice,omitempty"`
+
+	// InstanceInterruptionBehavior is what happens to an interrupted instance.
+	// Stop and hibernate require a persistent request.
+	// +kubebuilder:validation:Enum=terminate;stop;hibernate
+	InstanceInterruptionBehavior string `json:"instanceInterruptionBehavior,omitempty"`
+
+	// SpotInstanceType is one-time, or persistent to let AWS resume stopped
+	// or hibernated instances when capacity returns.
+	// +kubebuilder:validation:Enum=one-time;persistent
+	SpotInstanceType string `json:"spotInstanceType,omitempty"`
+}
+
+// UpdateStrategy controls how spec changes are rolled out to existing instances.
+type UpdateStrategy struct {
+	// MaxUnavailable is the maximum number of instances taken out of service at
+	// the same time, for example while they are stopped to change their
+	// instance type or repaired. Instances that are missing or not in the
+	// desired power state count too. In-place resizes and repairs always take
+	// at least one instance out of service. Replacements only take instances
+	// out of service when maxSurge is 0; otherwise old instances are kept
+	// until their replacements are ready. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	MaxUnavailable *int `json:"maxUnavailable,omitempty"`
+
+	// MaxSurge is the maximum number of replacement instances launched above
+	// the desired count while instances are replaced. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	MaxSurge *int `json:"maxSurge,omitempty"`
+}
+
+const (
+	// ScaleDownNewestFirst terminates the most recently launched instances first.
+	ScaleDownNewestFirst = "NewestFirst"
+	// ScaleDownOldestFirst terminates the longest running instances first.
+	ScaleDownOldestFirst = "OldestFirst"
+	// ScaleDownAvailabilityZone terminates from the availability zone with the
+	// most instances, keeping the remaining instances balanced across zones.
+	ScaleDownAvailabilityZone = "AvailabilityZone"
+)
+
+const (
+	// DeletionPolicyDelete terminates the instances and releases the
+	// addresses allocated for them.
+	DeletionPolicyDelete = "Delete"
+	// DeletionPolicyRetain keeps the instances and drops the ownership tags.
+	DeletionPolicyRetain = "Retain"
+	// DeletionPolicyStop stops the instances and keeps everything else.
+	DeletionPolicyStop = "Stop"
+)
+
+// HealthRemediation selects the repairs applied to unhealthy instances. Each
+// instance is repaired at most once every ten minutes, and repairs only start
+// while fewer than updateStrategy.maxUnavailable instances are out of service,
+// counting instances being resized or missing.
+type HealthRemediation struct {
+	// RebootOnInstanceCheckFailure reboots instances whose instance
+	// reachability check is impaired.
+	RebootOnInstanceCheckFailure bool `json:"rebootOnInstanceCheckFailure,omitempty"`
+
+	// StopStartOnSystemCheckFailure stops and starts instances whose system
+	// reachability check is impaired, which moves them to healthy hardware.
+	StopStartOnSystemCheckFailure bool `json:"stopStartOnSystemCheckFailure,omitempty"`
+
+	// StopStartOnRetirement stops and starts instances with a scheduled
+	// instance-retirement or instance-stop event instead of waiting for AWS
+	// to stop them.
+	StopStartOnRetirement bool `json:"stopStartOnRetirement,omitempty"`
+}
+
+const (
+	// DriftPolicyReport records drift without touching the drifted instances.
+	DriftPolicyReport = "Report"
+	// DriftPolicyCorrect reverts drifted attributes to the spec.
+	DriftPolicyCorrect = "Correct"
+	// DriftPolicyReplace terminates drifted instances and launches new ones.
+	DriftPolicyReplace = "Replace"
+)
+
+const (
+	// PowerStateRunning keeps the instances running.
+	PowerStateRunning = "Running"
+	// PowerStateStopped stops the instances.
+	PowerStateStopped = "Stopped"
+	// PowerStateHibernated hibernates the instances, saving their memory to the root volume.
+	PowerStateHibernated = "Hibernated"
+)
+
+// BlockDeviceMapping describes a block device attached to the instance at launch.
+type BlockDeviceMapping struct {
+	// DeviceName is the device name exposed to the instance, such as /dev/xvda.
+	DeviceName string `json:"deviceName"`
+
+	// Ebs configures the EBS volume backing the device.
+	Ebs *EbsBlockDevice `json:"ebs,omitempty"`
+}
+
+// EbsBlockDevice describes an EBS volume created for a block device mapping.
+type EbsBlockDevice struct {
+	// VolumeSize is the size of the volume in GiB.
+	VolumeSize int64 `json:"volumeSize,omitempty"`
+
+	// VolumeType is the EBS volume type, such as gp3, gp2, io2 or st1.
+	VolumeType string `json:"volumeType,omitempty"`
+
+	// Iops is the provisioned IOPS for io1, io2 and gp3 volumes.
+	Iops int64 `json:"iops,omitempty"`
+
+	// Throughput is the provisioned throughput in MiB/s for gp3 volumes.
+	Throughput int64 `json:"throughput,omitempty"`
+
+	// Encrypted enables EBS encryption for the volume.
+	Encrypted *bool `json:"encrypted,omitempty"`
+
+	// KmsKeyId is the KMS key used to encrypt the volume; the account default is used when empty.
+	KmsKeyId string `json:"kmsKeyId,omitempty"`
+
+	// SnapshotId is the snapshot the volume is created from.
+	SnapshotId string `json:"snapshotId,omitempty"`
+
+	// DeleteOnTermination deletes the volume when the instance terminates.
+	DeleteOnTermination *bool `json:"deleteOnTermination,omitempty"`
+}
+
+// NetworkInterface describes a network interface created for the instance at launch.
+type NetworkInterface struct {
+	// DeviceIndex is the attachment position of the interface; 0 is the primary interface.
+	DeviceIndex int64 `json:"deviceIndex"`
+
+	// SubnetId is the subnet the interface is created in.
+	SubnetId string `json:"subnetId,omitempty"`
+
+	// SecurityGroupIds are the security groups associated with the interface.
+	SecurityGroupIds []string `json:"securityGroupIds,omitempty"`
+
+	// SecondaryPrivateIpAddresses are explicit secondary private IPv4 addresses.
+	SecondaryPrivateIpAddresses []string `json:"secondaryPrivateIpAddresses,omitempty"`
+
+	// SecondaryPrivateIpAddressCount is the number of secondary private IPv4 addresses AWS assigns.
+	SecondaryPrivateIpAddressCount int64 `json:"secondaryPrivateIpAddressCount,omitempty"`
+
+	// Ipv6AddressCount is the number of IPv6 addresses AWS assigns from the subnet range.
+	Ipv6AddressCount int64 `json:"ipv6AddressCount,omitempty"`
+
+	// AssociatePublicIpAddress overrides the subnet public IP setting. Only valid for device index 0.
+	AssociatePublicIpAddress *bool `json:"associatePublicIpAddress,omitempty"`
+
+	// SourceDestCheck disables source/destination checking when false, as needed for NAT and routing appliances.
+	SourceDestCheck *bool `json:"sourceDestCheck,omitempty"`
+}
+
+// MetadataOptions configures the instance metadata service (IMDS) of the instances.
+type MetadataOptions struct {
+	// HttpTokens is "required" to enforce IMDSv2 or "optional" to also allow IMDSv1.
+	// +kubebuilder:validation:Enum=required;optional
+	HttpTokens string `json:"httpTokens,omitempty"`
+
+	// HttpPutResponseHopLimit is the maximum number of network hops for metadata token responses.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	HttpPutResponseHopLimit int64 `json:"httpPutResponseHopLimit,omitempty"`
+
+	// HttpEndpoint turns the metadata endpoint on or off.
+	// +kubebuilder:validation:Enum=enabled;disabled
+	HttpEndpoint string `json:"httpEndpoint,omitempty"`
+
+	// InstanceMetadataTags exposes the instance tags through the metadata service.
+	// +kubebuilder:validation:Enum=enabled;disabled
+	InstanceMetadataTags string `json:"instanceMetadataTags,omitempty"`
 }
 
 // VmStatus defines the observed state of Vm
 type VmStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-	Status         string           `json:"status,omitempty"`
+
+	// Status is a one-word summary of the Vm; Conditions carry the details.
+	Status string `json:"status,omitempty"`
+	// Deprecated: Error is no longer set. Failures are reported in the
+	// message of the Synced or Provisioned condition.
 	Error          string           `json:"error,omitempty"`
 	InstanceStatus []InstanceStatus `json:"instanceStatus,omitempty"`
+	// Conditions are the latest observations of the Vm state.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec the status reflects.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// AppliedGeneration is the generation of the spec the instances were
+	// last fully brought in line with. Drift is only detected against it.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
+	// Retries counts the reconciles that failed in a row; it drives the
+	// exponential backoff between retries.
+	Retries int `json:"retries,omitempty"`
+	// DryRun records the outcome of the last dry-run launch request.
+	DryRun *DryRunStatus `json:"dryRun,omitempty"`
+	// ManagedTagKeys are the tag keys last applied from the spec, used to
+	// find tags that have to be removed when the spec changes.
+	ManagedTagKeys []string `json:"managedTagKeys,omitempty"`
+	// ClientToken is the idempotency token of a launch in progress. It is
+	// persisted before RunInstances is called so that a retried launch returns
+	// the original reservation instead of starting new instances.
+	ClientToken string `json:"clientToken,omitempty"`
+	// Launches counts the completed launches; it keeps the client tokens of
+	// successive launches distinct.
+	Launches int `json:"launches,omitempty"`
+	// CurrentRevision is the revision of the spec every instance ran before
+	// the rollout in progress, if any.
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// UpdateRevision is the revision of the current spec. Instances of other
+	// revisions are replaced until CurrentRevision catches up with it.
+	UpdateRevision string `json:"updateRevision,omitempty"`
+	// LaunchTemplateVersion is the launch template version number the last
+	// launch resolved spec.launchTemplate.version to.
+	LaunchTemplateVersion string `json:"launchTemplateVersion,omitempty"`
+	// ResolvedImage is the AMI spec.imageSelector resolved to.
+	ResolvedImage *ResolvedImage `json:"resolvedImage,omitempty"`
+	// ResolvedSubnetId is the subnet spec.subnetSelector last resolved to.
+	ResolvedSubnetId string `json:"resolvedSubnetId,omitempty"`
+	// ResolvedSecurityGroupIds are the security groups
+	// spec.securityGroupSelector last resolved to.
+	ResolvedSecurityGroupIds []string `json:"resolvedSecurityGroupIds,omitempty"`
+	// CapacityFallback records how the last launch walked
+	// spec.capacityFallback.
+	CapacityFallback *CapacityFallbackStatus `json:"capacityFallback,omitempty"`
+}
+
+// CapacityFallbackStatus is the outcome of a launch that walked the capacity
+// fallback list.
+type CapacityFallbackStatus struct {
+	// InstanceType is the instance type the launch succeeded with.
+	InstanceType string `json:"instanceType,omitempty"`
+
+	// SubnetId is the subnet the launch succeeded in.
+	SubnetId string `json:"subnetId,omitempty"`
+
+	// Skipped are the combinations tried or ruled out before it.
+	Skipped []SkippedCapacity `json:"skipped,omitempty"`
+}
+
+// SkippedCapacity is a combination of instance type and subnet the launch
+// did not use.
+type SkippedCapacity struct {
+	InstanceType string `json:"instanceType,omitempty"`
+	SubnetId     string `json:"subnetId,omitempty"`
+	// Reason is why the combination was skipped, such as the AWS error code.
+	Reason string `json:"reason"`
+}
+
+// ResolvedImage records the AMI an image selector resolved to.
+type ResolvedImage struct {
+	// ImageId is the AMI the instances are launched from.
+	ImageId string `json:"imageId"`
+
+	// Selector describes the selector the AMI was resolved from; the AMI is
+	// resolved again when the selector changes.
+	Selector string `json:"selector"`
+}
+
+// DryRunStatus holds the result AWS returned for a dry-run RunInstances call.
+type DryRunStatus struct {
+	// Code is the AWS result code, DryRunOperation when the launch would succeed
+	// or UnauthorizedOperation when the credentials lack permission.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
 }
 
 type InstanceStatus struct {
@@ -59,8 +533,156 @@ type InstanceStatus struct {
 	State              string `json:"state,omitempty"`
 	PrivateIpAddresses string `json:"privateIpAddresses,omitempty"`
 	PublicIpAddresses  string `json:"publicIpAddresses,omitempty"`
+	InstanceType       string `json:"instanceType,omitempty"`
+	// PrivateIps are the private IPv4 addresses of every network interface;
+	// PrivateIpAddresses only holds the primary one.
+	PrivateIps []string `json:"privateIps,omitempty"`
+	// PublicIps are the public IPv4 addresses of every network interface;
+	// PublicIpAddresses only holds the primary one.
+	PublicIps []string `json:"publicIps,omitempty"`
+	// Ipv6Addresses are the IPv6 addresses of every network interface.
+	Ipv6Addresses []string `json:"ipv6Addresses,omitempty"`
+	// PrivateDnsName is the private DNS name of the instance.
+	PrivateDnsName string `json:"privateDnsName,omitempty"`
+	// PublicDnsName is the public DNS name of the instance, if it has one.
+	PublicDnsName string `json:"publicDnsName,omitempty"`
+	// AvailabilityZone is the zone the instance runs in.
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	// ImageId is the AMI the instance was launched from.
+	ImageId string `json:"imageId,omitempty"`
+	// LaunchTime is when the instance was last started.
+	LaunchTime *metav1.Time `json:"launchTime,omitempty"`
+	// StateTransitionReason explains the last state change, such as a user
+	// initiated shutdown.
+	StateTransitionReason string `json:"stateTransitionReason,omitempty"`
+	// VolumeIds are the EBS volumes attached to the instance.
+	VolumeIds []string `json:"volumeIds,omitempty"`
+	// NetworkInterfaceIds are the network interfaces attached to the instance.
+	NetworkInterfaceIds []string `json:"networkInterfaceIds,omitempty"`
+	// SubnetId is the subnet of the primary network interface.
+	SubnetId string `json:"subnetId,omitempty"`
+	// AllocationId is the Elastic IP address associated with the instance.
+	AllocationId string `json:"allocationId,omitempty"`
+	// AssociationId is the association of that address with the instance.
+	AssociationId string `json:"associationId,omitempty"`
+	// Revision is the revision of the spec the instance was launched from.
+	Revision string `json:"revision,omitempty"`
+	// Lifecycle is "spot" for spot instances and empty for on-demand ones.
+	Lifecycle string `json:"lifecycle,omitempty"`
+	// StateReason is the code of the reason for the last state change, such as
+	// Server.SpotInstanceTermination for an interrupted spot instance.
+	StateReason string `json:"stateReason,omitempty"`
+	// Update is the in-place update in progress on the instance, or the last
+	// one that failed.
+	Update *InstanceUpdate `json:"update,omitempty"`
+	// Drift lists the attributes of the instance that were changed outside
+	// the controller and no longer match the spec: State, InstanceType,
+	// SecurityGroups, Tags or MetadataOptions.
+	Drift []string `json:"drift,omitempty"`
+	// Health holds the EC2 status checks and scheduled events of the instance.
+	Health *InstanceHealth `json:"health,omitempty"`
+}
+
+// InstanceHealth is the result of the EC2 status checks of an instance.
+type InstanceHealth struct {
+	// SystemStatus is the system reachability check: ok, impaired,
+	// initializing, insufficient-data or not-applicable.
+	SystemStatus string `json:"systemStatus,omitempty"`
+
+	// InstanceStatus is the instance reachability check, with the same values
+	// as SystemStatus.
+	InstanceStatus string `json:"instanceStatus,omitempty"`
+
+	// EbsStatus is the worst status of the attached EBS volumes: ok,
+	// impaired or insufficient-data.
+	EbsStatus string `json:"ebsStatus,omitempty"`
+
+	// Events are the scheduled maintenance events that are still pending.
+	Events []ScheduledEvent `json:"events,omitempty"`
+
+	// LastRemediation is the last repair applied to the instance: Reboot or
+	// StopStart.
+	LastRemediation string `json:"lastRemediation,omitempty"`
+
+	// LastRemediationTime is when the last repair was applied.
+	LastRemediationTime *metav1.Time `json:"lastRemediationTime,omitempty"`
 }
 
+// ScheduledEvent is a maintenance event AWS scheduled for an instance.
+type ScheduledEvent struct {
+	// Code is the event type, such as instance-reboot, system-reboot,
+	// system-maintenance, instance-retirement or instance-stop.
+	Code string `json:"code"`
+
+	// Description explains the event.
+	Description string `json:"description,omitempty"`
+
+	// NotBefore is the earliest time the event can start.
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+
+	// NotAfter is the latest time the event can end.
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+}
+
+// InstanceUpdate tracks an in-place update of a single instance.
+type InstanceUpdate struct {
+	// Type is the kind of update, such as Resize.
+	Type string `json:"type"`
+
+	// Target is the value being applied, such as the new instance type.
+	Target string `json:"target,omitempty"`
+
+	// Phase is the current step of the update: Stopping, Starting or Failed.
+	Phase string `json:"phase"`
+
+	// Message explains why the update failed.
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	// InstanceUpdateResize changes the instance type with a stop-modify-start cycle.
+	InstanceUpdateResize = "Resize"
+	// InstanceUpdateStopStart moves an unhealthy instance to new hardware
+	// with a stop-start cycle.
+	InstanceUpdateStopStart = "StopStart"
+	// InstanceRemediationReboot reboots an instance that fails its instance
+	// reachability check.
+	InstanceRemediationReboot = "Reboot"
+
+	InstanceUpdateStopping = "Stopping"
+	InstanceUpdateStarting = "Starting"
+	InstanceUpdateFailed   = "Failed"
+)
+
+// OrphanInstancesAnnotation set to "true" on a deleting Vm releases it without
+// applying the deletion policy, leaving its instances in AWS. It is the way
+// out when the credentials Secret was deleted before the Vm, as happens when
+// the whole namespace is deleted.
+const OrphanInstancesAnnotation = "aws.my.controller/orphan-instances"
+
+// Condition types of a Vm.
+const (
+	// ConditionReady is True once every instance is in the desired power
+	// state and no scaling, rollout or in-place update is in progress.
+	ConditionReady = "Ready"
+	// ConditionProvisioned is True once the instances have been launched or adopted.
+	ConditionProvisioned = "Provisioned"
+	// ConditionSynced is True when the last reconcile applied the spec
+	// without errors; its message holds the error otherwise.
+	ConditionSynced = "Synced"
+	// ConditionDegraded is True while the Vm runs with fewer or impaired
+	// instances, such as after a spot interruption or a failed resize.
+	ConditionDegraded = "Degraded"
+	// ConditionDeleting is True while the instances are being deleted.
+	ConditionDeleting = "Deleting"
+	// ConditionHealthy is True when every running instance passes its EC2
+	// status checks. Pending scheduled events are listed in its message.
+	ConditionHealthy = "Healthy"
+	// ConditionFailed is True when AWS rejected the spec as invalid. Its
+	// reason is the AWS error code; the Vm is retried once the spec changes.
+	ConditionFailed = "Failed"
+)
+
 // CredentialsSecret defines the reference to the secret containing AWS credentials
 type CredentialsSecret struct {
 	// Name of the secret containing credentials
@@ -75,6 +697,9 @@ type CredentialsSecret struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Vm is the Schema for the vms API
 type Vm struct {
@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// liveInstanceStates are the states of instances that still count towards a Vm.
+var liveInstanceStates = []string{
+	ec2.InstanceStateNamePending,
+	ec2.InstanceStateNameRunning,
+	ec2.InstanceStateNameStopping,
+	ec2.InstanceStateNameStopped,
+}
+
+// DiscoverVM looks up the live instances carrying the ownership tags of the
+// Vm and records them in the VM status. It is used to recover instances when
+// the status was lost, instead of launching duplicates.
+func (c *AwsSession) DiscoverVM(vm *v1.Vm) error {
+	svc := ec2.New(c.sess)
+
+	input := &ec2.DescribeInstancesInput{Filters: c.ownedInstanceFilters(vm)}
+	instances, err := listInstances(svc, input)
+	if err != nil {
+		return fmt.Errorf("failed to discover instances owned by %s/%s: %w", vm.Namespace, vm.Name, err)
+	}
+	recordDiscovered(vm, instances)
+	return nil
+}
+
+// recordDiscovered records the discovered instances in the VM status. They
+// may come from a launch whose status update was lost, so the client token
+// of that launch is retired and the launch counted: the next launch gets a
+// token of its own instead of reusing one AWS accepted with other parameters.
+func recordDiscovered(vm *v1.Vm, instances []*ec2.Instance) {
+	vm.Status.InstanceStatus = nil
+	for _, instance := range instances {
+		vm.Status.InstanceStatus = append(vm.Status.InstanceStatus, instanceStatus(instance))
+	}
+	if len(instances) > 0 {
+		vm.Status.ClientToken = ""
+		vm.Status.Launches++
+	}
+}
+
+// ownedInstanceFilters matches the live instances carrying the ownership tags
+// of the Vm.
+func (c *AwsSession) ownedInstanceFilters(vm *v1.Vm) []*ec2.Filter {
+	return []*ec2.Filter{
+		{Name: aws.String("tag:" + clusterTagKey), Values: aws.StringSlice([]string{c.ClusterName})},
+		{Name: aws.String("tag:" + vmUIDTagKey), Values: aws.StringSlice([]string{string(vm.UID)})},
+		{Name: aws.String("instance-state-name"), Values: aws.StringSlice(liveInstanceStates)},
+	}
+}
+
+// AdoptVM brings the existing instances listed in spec.adoptInstanceIds under
+// the Vm by tagging them with its ownership and spec tags. Instances owned by
+// another Vm are refused.
+func (c *AwsSession) AdoptVM(vm *v1.Vm) error {
+	svc := ec2.New(c.sess)
+
+	instances, err := describeInstances(svc, vm.Spec.AdoptInstanceIds)
+	if err != nil {
+		return err
+	}
+
+	tags := c.launchTags(vm)
+	vm.Status.InstanceStatus = nil
+	for _, instance := range instances {
+		id := aws.StringValue(instance.InstanceId)
+		state := aws.StringValue(instance.State.Name)
+		if state == ec2.InstanceStateNameShuttingDown || state == ec2.InstanceStateNameTerminated {
+			return fmt.Errorf("cannot adopt instance %s in state %s", id, state)
+		}
+		for _, tag := range instance.Tags {
+			if aws.StringValue(tag.Key) == vmUIDTagKey && aws.StringValue(tag.Value) != string(vm.UID) {
+				return fmt.Errorf("cannot adopt instance %s: it is owned by another Vm (uid %s)", id, aws.StringValue(tag.Value))
+			}
+		}
+
+		_, err := svc.CreateTags(&ec2.CreateTagsInput{Resources: instanceResources(instance), Tags: ec2Tags(tags)})
+		if err != nil {
+			return fmt.Errorf("failed to tag adopted instance %s: %w", id, err)
+		}
+		adopted := instanceStatus(instance)
+		// Adopted instances take the revision of the Vm they join
+		adopted.Revision = ""
+		vm.Status.InstanceStatus = append(vm.Status.InstanceStatus, adopted)
+	}
+	vm.Status.ManagedTagKeys = sortedKeys(desiredTags(vm))
+	return nil
+}
@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+func TestRecordDiscovered(t *testing.T) {
+	running := &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)}
+	tests := []struct {
+		name         string
+		instances    []*ec2.Instance
+		wantRetired  bool
+		wantLaunches int
+	}{
+		{
+			name: "launch whose status update was lost",
+			instances: []*ec2.Instance{
+				{InstanceId: aws.String("i-1"), State: running},
+				{InstanceId: aws.String("i-2"), State: running},
+			},
+			wantRetired:  true,
+			wantLaunches: 3,
+		},
+		{
+			name:         "nothing launched yet",
+			wantRetired:  false,
+			wantLaunches: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &v1.Vm{}
+			vm.UID = "uid"
+			vm.Generation = 1
+			vm.Status.Launches = 2
+			token := LaunchToken(vm)
+			vm.Status.ClientToken = token
+
+			recordDiscovered(vm, tt.instances)
+			if got := len(vm.Status.InstanceStatus); got != len(tt.instances) {
+				t.Errorf("recorded %d instances, want %d", got, len(tt.instances))
+			}
+			if vm.Status.Launches != tt.wantLaunches {
+				t.Errorf("launches = %d, want %d", vm.Status.Launches, tt.wantLaunches)
+			}
+			if retired := vm.Status.ClientToken == ""; retired != tt.wantRetired {
+				t.Errorf("client token retired = %v, want %v", retired, tt.wantRetired)
+			}
+			// The next launch must not reuse the token of the recovered one
+			if tt.wantRetired && LaunchToken(vm) == token {
+				t.Errorf("next launch token reuses the recovered launch token %s", token)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// capacityErrors are the RunInstances error codes after which the next
+// capacity fallback combination is tried.
+var capacityErrors = map[string]bool{
+	"InsufficientInstanceCapacity":         true,
+	"InsufficientHostCapacity":             true,
+	"InsufficientReservedInstanceCapacity": true,
+	"InsufficientCapacityOnOutpost":        true,
+	"Unsupported":                          true,
+}
+
+// launchCandidate is a combination of instance type and subnet a launch can
+// be attempted with. Empty fields leave the choice to the spec defaults.
+type launchCandidate struct {
+	instanceType string
+	subnetId     string
+}
+
+// launchCandidates returns the combinations to try for a launch into the
+// given subnet, the spec instance type and subnet first. Spread launches keep
+// to their subnet.
+func launchCandidates(vm *v1.Vm, subnetId string) []launchCandidate {
+	types := []string{vm.Spec.InstanceType}
+	subnets := []string{subnetId}
+	if fallback := vm.Spec.CapacityFallback; fallback != nil {
+		types = appendUnique(types, fallback.InstanceTypes...)
+		if len(vm.Spec.SubnetIds) == 0 {
+			subnets = appendUnique(subnets, fallback.SubnetIds...)
+		}
+	}
+
+	candidates := make([]launchCandidate, 0, len(types)*len(subnets))
+	for _, instanceType := range types {
+		for _, subnet := range subnets {
+			candidates = append(candidates, launchCandidate{instanceType: instanceType, subnetId: subnet})
+		}
+	}
+	return candidates
+}
+
+// AcceptableInstanceType reports whether instances of the given type satisfy
+// the Vm, either as spec.instanceType or as one of its capacity fallbacks.
+func AcceptableInstanceType(vm *v1.Vm, instanceType string) bool {
+	if instanceType == vm.Spec.InstanceType {
+		return true
+	}
+	return vm.Spec.CapacityFallback != nil && containsString(vm.Spec.CapacityFallback.InstanceTypes, instanceType)
+}
+
+// runBatch launches a batch, walking the capacity fallback combinations
+// until one of them has capacity. The spec combination uses the batch client
+// token; every other combination derives its own, as AWS rejects a token
+// reused with different parameters. As a retry with the batch token alone
+// would not find a launch an earlier attempt made with a derived token, the
+// instances launched with any of the tokens are looked up first.
+func runBatch(svc *ec2.EC2, vm *v1.Vm, batch launchBatch, token string, tags map[string]string, launchTemplate *ec2.LaunchTemplateSpecification) ([]*ec2.Instance, error) {
+	candidates := launchCandidates(vm, batch.subnetId)
+	primary := candidates[0]
+	if tokens := candidateTokens(vm, candidates, token); len(tokens) > 1 {
+		instances, candidate, err := launchedWithTokens(svc, tokens)
+		if err != nil {
+			return nil, err
+		}
+		if len(instances) > 0 {
+			recordCapacityFallback(vm, candidate, nil)
+			return instances, nil
+		}
+	}
+	var skipped []v1.SkippedCapacity
+	if len(candidates) > 1 {
+		var err error
+		candidates, skipped, err = offeredCandidates(svc, candidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		runInput := newRunInstancesInput(vm, candidate.subnetId, tags)
+		runInput.LaunchTemplate = launchTemplate
+		if candidate.instanceType != "" {
+			runInput.InstanceType = aws.String(candidate.instanceType)
+		}
+		runInput.MinCount = aws.Int64(batch.minCount)
+		runInput.MaxCount = aws.Int64(batch.maxCount)
+		if token != "" {
+			runInput.ClientToken = aws.String(candidateToken(token, candidate, primary))
+		}
+
+		runOutput, err := svc.RunInstances(runInput)
+		if err != nil && runInput.InstanceMarketOptions != nil && fallbackToOnDemand(vm, err) {
+			runOutput, err = svc.RunInstances(onDemandInput(runInput))
+		}
+		if err == nil {
+			recordCapacityFallback(vm, candidate, skipped)
+			return runOutput.Instances, nil
+		}
+		aerr, ok := err.(awserr.Error)
+		if !ok || !capacityErrors[aerr.Code()] || vm.Spec.CapacityFallback == nil {
+			return nil, err
+		}
+		skipped = append(skipped, v1.SkippedCapacity{
+			InstanceType: candidate.instanceType,
+			SubnetId:     candidate.subnetId,
+			Reason:       aerr.Code() + ": " + aerr.Message(),
+		})
+		lastErr = err
+	}
+
+	recordCapacityFallback(vm, launchCandidate{}, skipped)
+	if lastErr == nil {
+		return nil, fmt.Errorf("no capacity fallback combination is offered in its availability zone")
+	}
+	return nil, fmt.Errorf("no capacity fallback combination has capacity: %w", lastErr)
+}
+
+// candidateToken returns the client token of a launch attempt with the
+// candidate, derived from the batch token unless it is the spec combination.
+func candidateToken(token string, candidate, primary launchCandidate) string {
+	if candidate == primary {
+		return token
+	}
+	sum := sha256.Sum256([]byte(token + "/" + candidate.instanceType + "/" + candidate.subnetId))
+	return fmt.Sprintf("%x", sum)
+}
+
+// candidateTokens maps the client token of every launch attempt a walk over
+// the candidates can make, including on-demand retries of spot launches, to
+// the candidate it launches. It is empty when the launch has no token.
+func candidateTokens(vm *v1.Vm, candidates []launchCandidate, token string) map[string]launchCandidate {
+	tokens := map[string]launchCandidate{}
+	if token == "" {
+		return tokens
+	}
+	onDemand := vm.Spec.InstanceMarketOptions != nil && vm.Spec.InstanceMarketOptions.FallbackToOnDemand
+	for _, candidate := range candidates {
+		attempt := candidateToken(token, candidate, candidates[0])
+		tokens[attempt] = candidate
+		if onDemand {
+			tokens[onDemandToken(attempt)] = candidate
+		}
+	}
+	return tokens
+}
+
+// launchedWithTokens returns the instances launched with one of the client
+// tokens and the candidate the token belongs to.
+func launchedWithTokens(svc *ec2.EC2, tokens map[string]launchCandidate) ([]*ec2.Instance, launchCandidate, error) {
+	values := make([]string, 0, len(tokens))
+	for token := range tokens {
+		values = append(values, token)
+	}
+	sort.Strings(values)
+	instances, err := listInstances(svc, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("client-token"), Values: aws.StringSlice(values)}},
+	})
+	if err != nil {
+		return nil, launchCandidate{}, fmt.Errorf("failed to look up instances by client token: %w", err)
+	}
+	if len(instances) == 0 {
+		return nil, launchCandidate{}, nil
+	}
+	// Only one attempt of a walk can have succeeded
+	token := aws.StringValue(instances[0].ClientToken)
+	var launched []*ec2.Instance
+	for _, instance := range instances {
+		if aws.StringValue(instance.ClientToken) == token {
+			launched = append(launched, instance)
+		}
+	}
+	return launched, tokens[token], nil
+}
+
+// recordCapacityFallback records the outcome of a fallback walk in the VM
+// status. Launches without fallbacks leave nothing behind.
+func recordCapacityFallback(vm *v1.Vm, winner launchCandidate, skipped []v1.SkippedCapacity) {
+	if vm.Spec.CapacityFallback == nil {
+		vm.Status.CapacityFallback = nil
+		return
+	}
+	vm.Status.CapacityFallback = &v1.CapacityFallbackStatus{
+		InstanceType: winner.instanceType,
+		SubnetId:     winner.subnetId,
+		Skipped:      skipped,
+	}
+}
+
+// offeredCandidates drops the combinations whose instance type is not offered
+// in the availability zone of the subnet, according to
+// DescribeInstanceTypeOfferings. Combinations without an explicit instance
+// type or subnet cannot be checked and are kept.
+func offeredCandidates(svc *ec2.EC2, candidates []launchCandidate) ([]launchCandidate, []v1.SkippedCapacity, error) {
+	var types, subnets []string
+	for _, candidate := range candidates {
+		if candidate.instanceType != "" {
+			types = appendUnique(types, candidate.instanceType)
+		}
+		if candidate.subnetId != "" {
+			subnets = appendUnique(subnets, candidate.subnetId)
+		}
+	}
+	if len(types) == 0 || len(subnets) == 0 {
+		return candidates, nil, nil
+	}
+
+	zones := map[string]string{}
+	output, err := svc.DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: aws.StringSlice(subnets)})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to describe subnets %v: %w", subnets, err)
+	}
+	for _, subnet := range output.Subnets {
+		zones[aws.StringValue(subnet.SubnetId)] = aws.StringValue(subnet.AvailabilityZone)
+	}
+
+	offered := map[string]bool{}
+	err = svc.DescribeInstanceTypeOfferingsPages(&ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(ec2.LocationTypeAvailabilityZone),
+		Filters:      []*ec2.Filter{{Name: aws.String("instance-type"), Values: aws.StringSlice(types)}},
+	}, func(page *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
+		for _, offering := range page.InstanceTypeOfferings {
+			offered[aws.StringValue(offering.InstanceType)+"/"+aws.StringValue(offering.Location)] = true
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to describe instance type offerings: %w", err)
+	}
+
+	var usable []launchCandidate
+	var skipped []v1.SkippedCapacity
+	for _, candidate := range candidates {
+		zone, known := zones[candidate.subnetId]
+		if candidate.instanceType == "" || !known || offered[candidate.instanceType+"/"+zone] {
+			usable = append(usable, candidate)
+			continue
+		}
+		skipped = append(skipped, v1.SkippedCapacity{
+			InstanceType: candidate.instanceType,
+			SubnetId:     candidate.subnetId,
+			Reason:       fmt.Sprintf("%s is not offered in %s", candidate.instanceType, zone),
+		})
+	}
+	return usable, skipped, nil
+}
+
+// appendUnique appends the values that are not in the slice yet.
+func appendUnique(values []string, more ...string) []string {
+	for _, value := range more {
+		if !containsString(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"testing"
+
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+func TestCandidateTokens(t *testing.T) {
+	primary := launchCandidate{instanceType: "m5.large", subnetId: "subnet-a"}
+	fallback := launchCandidate{instanceType: "m5a.large", subnetId: "subnet-a"}
+	tests := []struct {
+		name       string
+		market     *v1.InstanceMarketOptions
+		candidates []launchCandidate
+		token      string
+		want       int
+	}{
+		{
+			name:       "no token",
+			candidates: []launchCandidate{primary, fallback},
+			token:      "",
+			want:       0,
+		},
+		{
+			name:       "spec combination only",
+			candidates: []launchCandidate{primary},
+			token:      "token",
+			want:       1,
+		},
+		{
+			name:       "one token per candidate",
+			candidates: []launchCandidate{primary, fallback},
+			token:      "token",
+			want:       2,
+		},
+		{
+			name:       "spot without fallback",
+			market:     &v1.InstanceMarketOptions{},
+			candidates: []launchCandidate{primary, fallback},
+			token:      "token",
+			want:       2,
+		},
+		{
+			name:       "on-demand retries of spot launches",
+			market:     &v1.InstanceMarketOptions{FallbackToOnDemand: true},
+			candidates: []launchCandidate{primary, fallback},
+			token:      "token",
+			want:       4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &v1.Vm{}
+			vm.Spec.InstanceMarketOptions = tt.market
+			tokens := candidateTokens(vm, tt.candidates, tt.token)
+			if len(tokens) != tt.want {
+				t.Fatalf("candidateTokens() returned %d tokens, want %d", len(tokens), tt.want)
+			}
+			if tt.token == "" {
+				return
+			}
+			if got, ok := tokens[tt.token]; !ok || got != primary {
+				t.Errorf("batch token maps to %+v, want the spec combination", got)
+			}
+			for token, candidate := range tokens {
+				if len(token) > 64 {
+					t.Errorf("token for %+v is %d characters, AWS accepts 64", candidate, len(token))
+				}
+			}
+		})
+	}
+}
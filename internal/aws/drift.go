@@ -0,0 +1,274 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// Attributes compared by drift detection.
+const (
+	DriftState           = "State"
+	DriftInstanceType    = "InstanceType"
+	DriftSecurityGroups  = "SecurityGroups"
+	DriftTags            = "Tags"
+	DriftMetadataOptions = "MetadataOptions"
+)
+
+// Drift is an instance attribute that no longer matches the spec.
+type Drift struct {
+	InstanceId string
+	Field      string
+	Expected   string
+	Actual     string
+}
+
+// DetectDrift compares the instances described by GetExistingVM with the spec
+// and records the drifted attributes on each instance status. It returns the
+// drift that was not recorded in previous, the instance status before the
+// refresh.
+//
+// Nothing is reported until the current spec has been fully applied, as the
+// instances are expected to differ from it until then. The state and the
+// instance type only count as drift once they changed without the controller
+// changing them, so instances still waiting for an in-place update are not
+// reported; instances launched or being updated in place are skipped.
+// Instances AWS purged after a termination outside the controller are
+// reported as State drift.
+func (c *AwsSession) DetectDrift(vm *v1.Vm, previous []v1.InstanceStatus, instances []*ec2.Instance) []Drift {
+	for i := range vm.Status.InstanceStatus {
+		vm.Status.InstanceStatus[i].Drift = nil
+	}
+	if vm.Generation != vm.Status.AppliedGeneration || vm.Spec.DryRun {
+		return nil
+	}
+
+	known := make(map[string]v1.InstanceStatus, len(previous))
+	for _, instance := range previous {
+		known[instance.InstanceId] = instance
+	}
+	live := make(map[string]*ec2.Instance, len(instances))
+	for _, instance := range instances {
+		live[aws.StringValue(instance.InstanceId)] = instance
+	}
+
+	var drifts []Drift
+	desiredTags := c.launchTags(vm)
+	for i := range vm.Status.InstanceStatus {
+		status := &vm.Status.InstanceStatus[i]
+		before, seen := known[status.InstanceId]
+		if !seen || updating(*status) || spotInterrupted(*status) {
+			continue
+		}
+		instance, described := live[status.InstanceId]
+		if !described && status.State != ec2.InstanceStateNameTerminated {
+			// Launched too recently to be described
+			continue
+		}
+		var found []Drift
+		add := func(field, expected, actual string) {
+			found = append(found, Drift{InstanceId: status.InstanceId, Field: field, Expected: expected, Actual: actual})
+		}
+		flagged := func(field string) bool {
+			return containsString(before.Drift, field)
+		}
+
+		if expected := stateDrift(vm, *status, before); expected != "" {
+			add(DriftState, expected, status.State)
+		}
+		if status.State != ec2.InstanceStateNameRunning && status.State != ec2.InstanceStateNameStopped {
+			status.Drift = driftFields(found)
+			drifts = append(drifts, newDrift(found, before)...)
+			continue
+		}
+
+		if vm.Spec.InstanceType != "" && !AcceptableInstanceType(vm, status.InstanceType) &&
+			(status.InstanceType != before.InstanceType || flagged(DriftInstanceType)) {
+			add(DriftInstanceType, vm.Spec.InstanceType, status.InstanceType)
+		}
+		if desired := primaryGroupIds(vm); len(desired) > 0 {
+			if actual := liveGroupIds(instance); !equalStrings(desired, actual) {
+				add(DriftSecurityGroups, strings.Join(desired, ","), strings.Join(actual, ","))
+			}
+		}
+		if !tagsMatch(desiredTags, instance.Tags) {
+			add(DriftTags, "", "")
+		}
+		if vm.Spec.MetadataOptions != nil && !metadataOptionsMatch(vm.Spec.MetadataOptions, instance.MetadataOptions) {
+			add(DriftMetadataOptions, "", "")
+		}
+
+		status.Drift = driftFields(found)
+		drifts = append(drifts, newDrift(found, before)...)
+	}
+	return drifts
+}
+
+// stateDrift returns the state an instance was expected to be in when its
+// state changed outside the controller, or "" when it did not.
+func stateDrift(vm *v1.Vm, status, before v1.InstanceStatus) string {
+	desiredState := ec2.InstanceStateNameRunning
+	if !WantsRunning(vm) {
+		desiredState = ec2.InstanceStateNameStopped
+	}
+	switch status.State {
+	case ec2.InstanceStateNameShuttingDown, ec2.InstanceStateNameTerminated:
+		// Terminations by the controller are recorded before the refresh
+		if isLive(before.State) {
+			return before.State
+		}
+	case ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopped:
+		if status.State != desiredState && (before.State == desiredState || containsString(before.Drift, DriftState)) {
+			return desiredState
+		}
+	}
+	return ""
+}
+
+// SpecApplied reports whether the current spec has been fully applied to the
+// instances: nothing is being launched, scaled, rolled out, updated in place
+// or moved to another power state. SyncRevisions must have been called first.
+func SpecApplied(vm *v1.Vm) bool {
+	if ScaleDelta(vm) != 0 || RolloutInProgress(vm) || UpdateInProgress(vm) {
+		return false
+	}
+	for _, instance := range liveInstances(vm) {
+		if instance.State == ec2.InstanceStateNamePending || instance.State == ec2.InstanceStateNameStopping {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplaceDrifted terminates the live instances with drift, for the Replace
+// policy. The next scale step launches their replacements. Drifted instances
+// that are out of service go first; serving ones only within the
+// MaxUnavailable budget.
+func (c *AwsSession) ReplaceDrifted(vm *v1.Vm) error {
+	budget := unavailableBudget(vm)
+	var ids []*string
+	for _, instance := range liveInstances(vm) {
+		if len(instance.Drift) == 0 || updating(instance) {
+			continue
+		}
+		if serving(vm, instance) {
+			if budget <= 0 {
+				continue
+			}
+			budget--
+		}
+		ids = append(ids, aws.String(instance.InstanceId))
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	svc := ec2.New(c.sess)
+
+	output, err := svc.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: ids})
+	if err != nil {
+		return fmt.Errorf("failed to terminate drifted instances %v: %w", aws.StringValueSlice(ids), err)
+	}
+	recordStateChanges(vm, output.TerminatingInstances)
+	return nil
+}
+
+// SyncSecurityGroups re-applies the Vm security groups to the primary network
+// interface of every instance whose groups have drifted from the spec. The
+// instances are the ones described by GetExistingVM.
+func (c *AwsSession) SyncSecurityGroups(vm *v1.Vm, instances []*ec2.Instance) error {
+	desired := primaryGroupIds(vm)
+	if len(desired) == 0 || len(instances) == 0 {
+		return nil
+	}
+	svc := ec2.New(c.sess)
+
+	for _, instance := range instances {
+		id := aws.StringValue(instance.InstanceId)
+		if !isLive(recordedState(vm, id)) || reportOnly(vm, id) {
+			continue
+		}
+		if equalStrings(desired, liveGroupIds(instance)) {
+			continue
+		}
+		eni := primaryNetworkInterface(instance)
+		if eni == nil {
+			continue
+		}
+		_, err := svc.ModifyNetworkInterfaceAttribute(&ec2.ModifyNetworkInterfaceAttributeInput{
+			NetworkInterfaceId: eni.NetworkInterfaceId,
+			Groups:             aws.StringSlice(desired),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set security groups of %s: %w", aws.StringValue(instance.InstanceId), err)
+		}
+	}
+	return nil
+}
+
+// reportOnly reports whether the instance has drifted under the Report
+// policy, in which case the controller leaves it as it is.
+func reportOnly(vm *v1.Vm, instanceId string) bool {
+	if vm.Spec.DriftPolicy != v1.DriftPolicyReport {
+		return false
+	}
+	for _, instance := range vm.Status.InstanceStatus {
+		if instance.InstanceId == instanceId {
+			return len(instance.Drift) > 0
+		}
+	}
+	return false
+}
+
+// primaryGroupIds returns the sorted security groups the primary network
+// interface should have.
+func primaryGroupIds(vm *v1.Vm) []string {
+	groupIds := securityGroupIds(vm)
+	for _, nic := range vm.Spec.NetworkInterfaces {
+		if nic.DeviceIndex == 0 && len(nic.SecurityGroupIds) > 0 {
+			groupIds = nic.SecurityGroupIds
+		}
+	}
+	sorted := append([]string(nil), groupIds...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// liveGroupIds returns the sorted security groups of the primary network
+// interface of the instance.
+func liveGroupIds(instance *ec2.Instance) []string {
+	var groupIds []string
+	if eni := primaryNetworkInterface(instance); eni != nil {
+		for _, group := range eni.Groups {
+			groupIds = append(groupIds, aws.StringValue(group.GroupId))
+		}
+	}
+	sort.Strings(groupIds)
+	return groupIds
+}
+
+func isLive(state string) bool {
+	return containsString(liveInstanceStates, state)
+}
+
+func driftFields(drifts []Drift) []string {
+	var fields []string
+	for _, drift := range drifts {
+		fields = append(fields, drift.Field)
+	}
+	return fields
+}
+
+// newDrift filters out the drift already recorded on the instance before.
+func newDrift(drifts []Drift, before v1.InstanceStatus) []Drift {
+	var fresh []Drift
+	for _, drift := range drifts {
+		if !containsString(before.Drift, drift.Field) {
+			fresh = append(fresh, drift)
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,244 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDetectDrift(t *testing.T) {
+	running, stopped := ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopped
+	status := func(state, instanceType string, drift ...string) v1.InstanceStatus {
+		return v1.InstanceStatus{InstanceId: "i-1", State: state, InstanceType: instanceType, Drift: drift}
+	}
+	resizing := status(stopped, "m5.large")
+	resizing.Update = &v1.InstanceUpdate{Type: v1.InstanceUpdateResize, Target: "m5.xlarge", Phase: v1.InstanceUpdateStopping}
+	tests := []struct {
+		name      string
+		applied   int64
+		spec      string
+		previous  []v1.InstanceStatus
+		current   v1.InstanceStatus
+		missing   bool
+		wantDrift []string
+		wantNew   int
+	}{
+		{
+			name:     "in line with the spec",
+			applied:  2,
+			spec:     "m5.large",
+			previous: []v1.InstanceStatus{status(running, "m5.large")},
+			current:  status(running, "m5.large"),
+		},
+		{
+			name:     "spec change not applied yet",
+			applied:  1,
+			spec:     "m5.xlarge",
+			previous: []v1.InstanceStatus{status(running, "m5.large", DriftState)},
+			current:  status(stopped, "m5.large"),
+		},
+		{
+			name:     "instance waiting for its resize",
+			applied:  2,
+			spec:     "m5.xlarge",
+			previous: []v1.InstanceStatus{status(running, "m5.large")},
+			current:  status(running, "m5.large"),
+		},
+		{
+			name:     "instance being resized",
+			applied:  2,
+			spec:     "m5.xlarge",
+			previous: []v1.InstanceStatus{status(running, "m5.large")},
+			current:  resizing,
+		},
+		{
+			name:      "instance type changed outside the controller",
+			applied:   2,
+			spec:      "m5.large",
+			previous:  []v1.InstanceStatus{status(running, "m5.large")},
+			current:   status(running, "c5.large"),
+			wantDrift: []string{DriftInstanceType},
+			wantNew:   1,
+		},
+		{
+			name:      "reported instance type drift stays reported",
+			applied:   2,
+			spec:      "m5.large",
+			previous:  []v1.InstanceStatus{status(running, "c5.large", DriftInstanceType)},
+			current:   status(running, "c5.large"),
+			wantDrift: []string{DriftInstanceType},
+			wantNew:   0,
+		},
+		{
+			name:      "stopped outside the controller",
+			applied:   2,
+			spec:      "m5.large",
+			previous:  []v1.InstanceStatus{status(running, "m5.large")},
+			current:   status(stopped, "m5.large"),
+			wantDrift: []string{DriftState},
+			wantNew:   1,
+		},
+		{
+			name:     "stop not reached yet",
+			applied:  2,
+			spec:     "m5.large",
+			previous: []v1.InstanceStatus{status(ec2.InstanceStateNamePending, "m5.large")},
+			current:  status(stopped, "m5.large"),
+		},
+		{
+			name:      "terminated outside the controller",
+			applied:   2,
+			spec:      "m5.large",
+			previous:  []v1.InstanceStatus{status(running, "m5.large")},
+			current:   status(ec2.InstanceStateNameTerminated, "m5.large"),
+			wantDrift: []string{DriftState},
+			wantNew:   1,
+		},
+		{
+			name:     "terminated by the controller",
+			applied:  2,
+			spec:     "m5.large",
+			previous: []v1.InstanceStatus{status(ec2.InstanceStateNameShuttingDown, "m5.large")},
+			current:  status(ec2.InstanceStateNameTerminated, "m5.large"),
+		},
+		{
+			name:      "purged after a termination outside the controller",
+			applied:   2,
+			spec:      "m5.large",
+			previous:  []v1.InstanceStatus{status(running, "m5.large")},
+			current:   status(ec2.InstanceStateNameTerminated, "m5.large"),
+			missing:   true,
+			wantDrift: []string{DriftState},
+			wantNew:   1,
+		},
+		{
+			name:     "launched too recently to be described",
+			applied:  2,
+			spec:     "m5.large",
+			previous: []v1.InstanceStatus{status(ec2.InstanceStateNamePending, "m5.large")},
+			current:  status(ec2.InstanceStateNamePending, "m5.large"),
+			missing:  true,
+		},
+		{
+			name:    "new instance",
+			applied: 2,
+			spec:    "m5.large",
+			current: status(stopped, "c5.large"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &AwsSession{ClusterName: "test"}
+			vm := &v1.Vm{}
+			vm.Generation = 2
+			vm.Spec.InstanceType = tt.spec
+			vm.Status.AppliedGeneration = tt.applied
+			vm.Status.InstanceStatus = []v1.InstanceStatus{tt.current}
+			vm.Status.InstanceStatus[0].Drift = nil
+			instances := []*ec2.Instance{{
+				InstanceId:   aws.String("i-1"),
+				InstanceType: aws.String(tt.current.InstanceType),
+				State:        &ec2.InstanceState{Name: aws.String(tt.current.State)},
+				Tags:         ec2Tags(c.launchTags(vm)),
+			}}
+			if tt.missing {
+				instances = nil
+			}
+
+			drifts := c.DetectDrift(vm, tt.previous, instances)
+			if got := vm.Status.InstanceStatus[0].Drift; !reflect.DeepEqual(got, tt.wantDrift) {
+				t.Errorf("drift = %v, want %v", got, tt.wantDrift)
+			}
+			if len(drifts) != tt.wantNew {
+				t.Errorf("DetectDrift() returned %d new drifts, want %d", len(drifts), tt.wantNew)
+			}
+		})
+	}
+}
+
+func TestUnavailableBudget(t *testing.T) {
+	running, stopped := ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopped
+	resizing := instance("i-3", stopped, "")
+	resizing.Update = &v1.InstanceUpdate{Type: v1.InstanceUpdateResize, Phase: v1.InstanceUpdateStopping}
+	tests := []struct {
+		name           string
+		maxUnavailable int
+		instances      []v1.InstanceStatus
+		want           int
+	}{
+		{
+			name:           "all serving",
+			maxUnavailable: 1,
+			instances:      []v1.InstanceStatus{instance("i-1", running, ""), instance("i-2", running, ""), instance("i-3", running, "")},
+			want:           1,
+		},
+		{
+			name:           "update in progress",
+			maxUnavailable: 1,
+			instances:      []v1.InstanceStatus{instance("i-1", running, ""), instance("i-2", running, ""), resizing},
+			want:           0,
+		},
+		{
+			name:           "missing instance",
+			maxUnavailable: 2,
+			instances:      []v1.InstanceStatus{instance("i-1", running, ""), instance("i-2", running, "")},
+			want:           1,
+		},
+		{
+			name:           "not in the desired power state",
+			maxUnavailable: 1,
+			instances:      []v1.InstanceStatus{instance("i-1", running, ""), instance("i-2", stopped, ""), instance("i-3", running, "")},
+			want:           0,
+		},
+		{
+			name:           "at least one without unavailability",
+			maxUnavailable: 0,
+			instances:      []v1.InstanceStatus{instance("i-1", running, ""), instance("i-2", running, ""), instance("i-3", running, "")},
+			want:           1,
+		},
+		{
+			name:           "surge instances do not add budget",
+			maxUnavailable: 1,
+			instances: []v1.InstanceStatus{instance("i-1", running, ""), instance("i-2", running, ""), instance("i-3", running, ""),
+				instance("i-4", running, "")},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := rolloutVm(3, 1, tt.maxUnavailable, tt.instances...)
+			if got := unavailableBudget(vm); got != tt.want {
+				t.Errorf("unavailableBudget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurged(t *testing.T) {
+	launchedAt := func(ago time.Duration) *metav1.Time {
+		launchTime := metav1.NewTime(time.Now().Add(-ago))
+		return &launchTime
+	}
+	tests := []struct {
+		name       string
+		launchTime *metav1.Time
+		want       bool
+	}{
+		{name: "launched long ago", launchTime: launchedAt(2 * time.Hour), want: true},
+		{name: "launched just now", launchTime: launchedAt(10 * time.Second), want: false},
+		{name: "launch time unknown", launchTime: nil, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := v1.InstanceStatus{InstanceId: "i-1", LaunchTime: tt.launchTime}
+			if got := purged(instance); got != tt.want {
+				t.Errorf("purged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// SyncElasticIps associates an Elastic IP address with every running or
+// stopped instance that has none, taking the spec addresses first and
+// allocating new ones when spec.elasticIp.allocate is set. Allocated addresses
+// left unassociated once every instance has one are released.
+func (c *AwsSession) SyncElasticIps(vm *v1.Vm) error {
+	if vm.Spec.ElasticIp == nil || vm.Spec.DryRun {
+		return nil
+	}
+	svc := ec2.New(c.sess)
+
+	referenced, err := describeAddresses(svc, &ec2.DescribeAddressesInput{
+		AllocationIds: aws.StringSlice(vm.Spec.ElasticIp.AllocationIds),
+	}, len(vm.Spec.ElasticIp.AllocationIds) > 0)
+	if err != nil {
+		return err
+	}
+	allocated, err := c.allocatedAddresses(svc, vm)
+	if err != nil {
+		return err
+	}
+
+	byInstance := map[string]*ec2.Address{}
+	seen := map[string]bool{}
+	var free []*ec2.Address
+	for _, address := range append(referenced, allocated...) {
+		if seen[aws.StringValue(address.AllocationId)] {
+			continue
+		}
+		seen[aws.StringValue(address.AllocationId)] = true
+		switch instanceId := aws.StringValue(address.InstanceId); {
+		case instanceId != "":
+			byInstance[instanceId] = address
+		case address.AssociationId == nil:
+			free = append(free, address)
+		}
+	}
+
+	missing := 0
+	for i := range vm.Status.InstanceStatus {
+		instance := &vm.Status.InstanceStatus[i]
+		instance.AllocationId, instance.AssociationId = "", ""
+		if address, ok := byInstance[instance.InstanceId]; ok {
+			instance.AllocationId = aws.StringValue(address.AllocationId)
+			instance.AssociationId = aws.StringValue(address.AssociationId)
+			continue
+		}
+		if instance.State != ec2.InstanceStateNameRunning && instance.State != ec2.InstanceStateNameStopped {
+			if instance.State == ec2.InstanceStateNamePending {
+				missing++
+			}
+			continue
+		}
+
+		var allocationId *string
+		if len(free) > 0 {
+			allocationId, free = free[0].AllocationId, free[1:]
+		} else if vm.Spec.ElasticIp.Allocate {
+			output, err := svc.AllocateAddress(&ec2.AllocateAddressInput{
+				Domain:            aws.String(ec2.DomainTypeVpc),
+				TagSpecifications: resourceTagSpecifications(ec2.ResourceTypeElasticIp, c.launchTags(vm)),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to allocate an Elastic IP for %s: %w", instance.InstanceId, err)
+			}
+			allocationId = output.AllocationId
+		} else {
+			// Every spec address is in use
+			missing++
+			continue
+		}
+
+		// AWS only accepts an instance ID for instances with a single network
+		// interface, so the address goes onto the primary one
+		networkInterfaceId, err := primaryNetworkInterfaceId(svc, instance.InstanceId)
+		if err != nil {
+			return err
+		}
+		output, err := svc.AssociateAddress(&ec2.AssociateAddressInput{
+			AllocationId:       allocationId,
+			NetworkInterfaceId: aws.String(networkInterfaceId),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to associate Elastic IP %s with %s: %w", aws.StringValue(allocationId), instance.InstanceId, err)
+		}
+		instance.AllocationId = aws.StringValue(allocationId)
+		instance.AssociationId = aws.StringValue(output.AssociationId)
+	}
+
+	if missing > 0 {
+		return nil
+	}
+	for _, address := range free {
+		if !ownedAddress(vm, address) || containsString(vm.Spec.ElasticIp.AllocationIds, aws.StringValue(address.AllocationId)) {
+			continue
+		}
+		if err := releaseAddress(svc, address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// primaryNetworkInterfaceId returns the ID of the network interface at device
+// index 0 of the instance.
+func primaryNetworkInterfaceId(svc *ec2.EC2, instanceId string) (string, error) {
+	instances, err := describeInstances(svc, []string{instanceId})
+	if err != nil {
+		return "", err
+	}
+	for _, instance := range instances {
+		if eni := primaryNetworkInterface(instance); eni != nil {
+			return aws.StringValue(eni.NetworkInterfaceId), nil
+		}
+	}
+	return "", fmt.Errorf("instance %s has no primary network interface", instanceId)
+}
+
+// ReleaseElasticIps disassociates and releases the addresses the controller
+// allocated for the Vm. Addresses listed in the spec are left alone.
+func (c *AwsSession) ReleaseElasticIps(vm *v1.Vm) error {
+	svc := ec2.New(c.sess)
+
+	allocated, err := c.allocatedAddresses(svc, vm)
+	if err != nil {
+		return err
+	}
+	for _, address := range allocated {
+		if address.AssociationId != nil {
+			_, err := svc.DisassociateAddress(&ec2.DisassociateAddressInput{AssociationId: address.AssociationId})
+			if err != nil {
+				return fmt.Errorf("failed to disassociate Elastic IP %s: %w", aws.StringValue(address.AllocationId), err)
+			}
+		}
+		if err := releaseAddress(svc, address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocatedAddresses returns the addresses carrying the ownership tags of the Vm.
+func (c *AwsSession) allocatedAddresses(svc *ec2.EC2, vm *v1.Vm) ([]*ec2.Address, error) {
+	return describeAddresses(svc, &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("tag:" + clusterTagKey), Values: aws.StringSlice([]string{c.ClusterName})},
+			{Name: aws.String("tag:" + vmUIDTagKey), Values: aws.StringSlice([]string{string(vm.UID)})},
+		},
+	}, true)
+}
+
+// describeAddresses returns the addresses matching the input, or none when
+// the lookup is disabled, as DescribeAddresses without input lists them all.
+func describeAddresses(svc *ec2.EC2, input *ec2.DescribeAddressesInput, enabled bool) ([]*ec2.Address, error) {
+	if !enabled {
+		return nil, nil
+	}
+	output, err := svc.DescribeAddresses(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe Elastic IPs: %w", err)
+	}
+	return output.Addresses, nil
+}
+
+// ownedAddress reports whether the controller allocated the address for the Vm.
+func ownedAddress(vm *v1.Vm, address *ec2.Address) bool {
+	return tagValue(address.Tags, vmUIDTagKey) == string(vm.UID)
+}
+
+func releaseAddress(svc *ec2.EC2, address *ec2.Address) error {
+	_, err := svc.ReleaseAddress(&ec2.ReleaseAddressInput{AllocationId: address.AllocationId})
+	if err != nil {
+		return fmt.Errorf("failed to release Elastic IP %s: %w", aws.StringValue(address.AllocationId), err)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
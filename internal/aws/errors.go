@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// ErrorClass groups AWS errors by how the controller should react to them.
+type ErrorClass string
+
+const (
+	// ErrorThrottling means the API rate limit was hit.
+	ErrorThrottling ErrorClass = "Throttling"
+	// ErrorTransient covers AWS server errors, network failures and
+	// eventual consistency, which usually clear up on their own.
+	ErrorTransient ErrorClass = "Transient"
+	// ErrorCapacity means AWS has no capacity for the request right now.
+	ErrorCapacity ErrorClass = "Capacity"
+	// ErrorQuota means an account limit was reached.
+	ErrorQuota ErrorClass = "Quota"
+	// ErrorAuth means the credentials are invalid or lack permission.
+	ErrorAuth ErrorClass = "Auth"
+	// ErrorInvalid means the request built from the spec is invalid and will
+	// keep failing until the spec changes.
+	ErrorInvalid ErrorClass = "InvalidParameter"
+	// ErrorUnknown is any other error.
+	ErrorUnknown ErrorClass = "Unknown"
+)
+
+var transientErrors = map[string]bool{
+	"InternalError":                      true,
+	"InternalFailure":                    true,
+	"ServiceUnavailable":                 true,
+	"Unavailable":                        true,
+	"RequestTimeout":                     true,
+	"RequestTimeoutException":            true,
+	request.ErrCodeRequestError:          true,
+	request.ErrCodeResponseTimeout:       true,
+	"IncorrectState":                     true,
+	"IncorrectInstanceState":             true,
+	"InvalidInstanceID.NotFound":         true,
+	"InvalidNetworkInterfaceID.NotFound": true,
+	"InvalidAllocationID.NotFound":       true,
+	"InvalidAssociationID.NotFound":      true,
+}
+
+var authErrors = map[string]bool{
+	"AuthFailure":           true,
+	"UnauthorizedOperation": true,
+	"InvalidClientTokenId":  true,
+	"SignatureDoesNotMatch": true,
+	"AccessDenied":          true,
+	"AccessDeniedException": true,
+	"ExpiredToken":          true,
+	"RequestExpired":        true,
+	"OptInRequired":         true,
+	"Blocked":               true,
+}
+
+// ErrorCode returns the AWS error code wrapped in err, or an empty string.
+func ErrorCode(err error) string {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code()
+	}
+	return ""
+}
+
+// Classify sorts an error returned by the AWS layer into an ErrorClass.
+func Classify(err error) ErrorClass {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return ErrorUnknown
+	}
+	code := aerr.Code()
+	var failure awserr.RequestFailure
+	switch {
+	case request.IsErrorThrottle(aerr):
+		return ErrorThrottling
+	case transientErrors[code]:
+		return ErrorTransient
+	case errors.As(err, &failure) && failure.StatusCode() >= 500:
+		return ErrorTransient
+	case capacityErrors[code] || spotCapacityErrors[code]:
+		return ErrorCapacity
+	case strings.HasSuffix(code, "LimitExceeded"):
+		return ErrorQuota
+	case authErrors[code]:
+		return ErrorAuth
+	case strings.HasPrefix(code, "Invalid") || strings.HasPrefix(code, "Missing") ||
+		code == "IdempotentParameterMismatch" || code == "ValidationError" || code == "ValidationException" ||
+		code == "ParameterNotFound":
+		return ErrorInvalid
+	}
+	return ErrorUnknown
+}
+
+// Retryable reports whether the error may clear up without a spec change.
+func (class ErrorClass) Retryable() bool {
+	return class != ErrorInvalid
+}
@@ -0,0 +1,61 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{name: "throttling", err: awserr.New("Throttling", "Rate exceeded", nil), want: ErrorThrottling},
+		{name: "request limit is throttling, not quota", err: awserr.New("RequestLimitExceeded", "", nil), want: ErrorThrottling},
+		{name: "server error", err: awserr.New("InternalError", "", nil), want: ErrorTransient},
+		{name: "5xx status", err: awserr.NewRequestFailure(awserr.New("Unexpected", "", nil), 503, "req"), want: ErrorTransient},
+		{name: "eventual consistency", err: awserr.New("InvalidInstanceID.NotFound", "", nil), want: ErrorTransient},
+		{name: "capacity", err: awserr.New("InsufficientInstanceCapacity", "", nil), want: ErrorCapacity},
+		{name: "spot capacity", err: awserr.New("InsufficientSpotCapacity", "", nil), want: ErrorCapacity},
+		{name: "quota", err: awserr.New("InstanceLimitExceeded", "", nil), want: ErrorQuota},
+		{name: "auth", err: awserr.New("UnauthorizedOperation", "", nil), want: ErrorAuth},
+		{name: "invalid parameter", err: awserr.New("InvalidAMIID.NotFound", "", nil), want: ErrorInvalid},
+		{name: "missing parameter", err: awserr.New("MissingParameter", "", nil), want: ErrorInvalid},
+		{name: "idempotency mismatch", err: awserr.New("IdempotentParameterMismatch", "", nil), want: ErrorInvalid},
+		{name: "wrapped", err: fmt.Errorf("failed to terminate instances: %w", awserr.New("InvalidParameterValue", "", nil)), want: ErrorInvalid},
+		{name: "unknown code", err: awserr.New("SomethingElse", "", nil), want: ErrorUnknown},
+		{name: "not an AWS error", err: errors.New("boom"), want: ErrorUnknown},
+		{name: "AWS error formatted away", err: fmt.Errorf("failed: %v", awserr.New("InvalidParameterValue", "", nil)), want: ErrorUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for _, class := range []ErrorClass{ErrorThrottling, ErrorTransient, ErrorCapacity, ErrorQuota, ErrorAuth, ErrorUnknown} {
+		if !class.Retryable() {
+			t.Errorf("%s is not retryable", class)
+		}
+	}
+	if ErrorInvalid.Retryable() {
+		t.Errorf("%s is retryable", ErrorInvalid)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	if got := ErrorCode(fmt.Errorf("wrapped: %w", awserr.New("InvalidAMIID.Malformed", "", nil))); got != "InvalidAMIID.Malformed" {
+		t.Errorf("ErrorCode() = %q, want InvalidAMIID.Malformed", got)
+	}
+	if got := ErrorCode(errors.New("boom")); got != "" {
+		t.Errorf("ErrorCode() = %q, want empty", got)
+	}
+}
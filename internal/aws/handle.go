@@ -2,15 +2,25 @@ package aws
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// launchVisibilityDelay is how long a launched instance may take to show up
+// in DescribeInstances, which is eventually consistent.
+const launchVisibilityDelay = 5 * time.Minute
+
 type AwsSession struct {
 	sess *session.Session
+
+	// ClusterName identifies the cluster in the ownership tags of the instances.
+	ClusterName string
 }
 
 // NewAwsSession creates a new AWS session.
@@ -22,104 +32,405 @@ func NewAwsSession(sess *session.Session) *AwsSession {
 
 // CreateVM creates a new EC2 instance with given specs.
 func (c *AwsSession) CreateVM(vm *v1.Vm) error {
+	// RunInstances rejects a MinCount below one
+	minCount := vm.Spec.MinCount
+	if minCount < 1 {
+		minCount = 1
+	}
+	return c.launch(vm, int64(minCount), int64(DesiredCount(vm)))
+}
+
+// launch runs between minCount and maxCount instances from the Vm spec and
+// appends them to the VM status. When the Vm spreads over several subnets the
+// launch is split into one RunInstances call per subnet.
+func (c *AwsSession) launch(vm *v1.Vm, minCount, maxCount int64) error {
 	svc := ec2.New(c.sess)
 
 	// Specifying instance details
-	runInput := &ec2.RunInstancesInput{
-		ImageId:      aws.String(vm.Spec.ImageId),
-		InstanceType: aws.String(vm.Spec.InstanceType),
-		MinCount:     aws.Int64(int64(vm.Spec.MinCount)),
-		MaxCount:     aws.Int64(int64(vm.Spec.MaxCount)),
-		KeyName:      aws.String(vm.Spec.KeyName),
-		SubnetId:     aws.String(vm.Spec.SubnetId),
+	revision := Revision(vm)
+	tags := c.launchTags(vm)
+	tags[revisionTagKey] = revision
+	batches := launchBatches(vm, minCount, maxCount)
+
+	var launchTemplate *ec2.LaunchTemplateSpecification
+	if vm.Spec.LaunchTemplate != nil {
+		var err error
+		launchTemplate, err = resolveLaunchTemplate(svc, vm.Spec.LaunchTemplate)
+		if err != nil {
+			return err
+		}
 	}
 
-	runOutput, err := svc.RunInstances(runInput)
-	if err != nil {
-		fmt.Printf("Error creating EC2 instance: %v\n", err)
-		return err
+	if vm.Spec.DryRun {
+		runInput := newRunInstancesInput(vm, batches[0].subnetId, tags)
+		runInput.LaunchTemplate = launchTemplate
+		runInput.MinCount = aws.Int64(minCount)
+		runInput.MaxCount = aws.Int64(maxCount)
+		runInput.DryRun = aws.Bool(true)
+		_, err := svc.RunInstances(runInput)
+		return recordDryRun(vm, err)
 	}
 
-	_, errtag := svc.CreateTags(&ec2.CreateTagsInput{
-		Resources: []*string{runOutput.Instances[0].InstanceId},
-		Tags: []*ec2.Tag{
-			{
-				Key:   aws.String("Name"),
-				Value: aws.String(vm.Spec.Name),
-			},
-		},
-	})
-	if errtag != nil {
-		fmt.Println("Could not create tags for instance", runOutput.Instances[0].InstanceId, errtag)
-		return nil
+	// Nothing is recorded until every batch succeeded; a retry with the same
+	// client tokens returns the reservations that were already made
+	var launched []*ec2.Instance
+	for _, batch := range batches {
+		token := vm.Status.ClientToken
+		if token != "" && len(batches) > 1 {
+			token = batchToken(token, batch.subnetId)
+		}
+		instances, err := runBatch(svc, vm, batch, token, tags, launchTemplate)
+		if err != nil {
+			fmt.Printf("Error creating EC2 instance: %v\n", err)
+			return err
+		}
+		launched = append(launched, instances...)
+	}
+	vm.Status.DryRun = nil
+	vm.Status.ClientToken = ""
+	vm.Status.Launches++
+	if launchTemplate != nil {
+		vm.Status.LaunchTemplateVersion = aws.StringValue(launchTemplate.Version)
 	}
 
+	vm.Status.ManagedTagKeys = sortedKeys(desiredTags(vm))
+
 	// Store instance ID in VM status
-	for i := range runOutput.Instances {
-		vm.Status.InstanceStatus = append(vm.Status.InstanceStatus, v1.InstanceStatus{
-			InstanceId: *runOutput.Instances[i].InstanceId,
-			State:      *runOutput.Instances[i].State.Name,
-		})
+	for i := range launched {
+		instance := instanceStatus(launched[i])
+		instance.Revision = revision
+		vm.Status.InstanceStatus = append(vm.Status.InstanceStatus, instance)
 	}
 
 	return nil
 }
 
-// GetExistingVM gets the existing EC2 instance details.
-func (c *AwsSession) GetExistingVM(vm *v1.Vm) error {
+// SyncSourceDestCheck applies the SourceDestCheck setting of each Vm network
+// interface, which RunInstances does not accept at launch, to the interfaces
+// that do not match it yet. The instances are the ones described by
+// GetExistingVM, so instances launched during the reconcile are set up by
+// the next one.
+func (c *AwsSession) SyncSourceDestCheck(vm *v1.Vm, instances []*ec2.Instance) error {
+	if len(instances) == 0 {
+		return nil
+	}
 	svc := ec2.New(c.sess)
 
-	instancesIds := make([]string, len(vm.Status.InstanceStatus))
-	for i, id := range vm.Status.InstanceStatus {
-		instancesIds[i] = id.InstanceId
+	for _, nic := range vm.Spec.NetworkInterfaces {
+		if nic.SourceDestCheck == nil {
+			continue
+		}
+		for _, instance := range instances {
+			id := aws.StringValue(instance.InstanceId)
+			if !isLive(recordedState(vm, id)) || reportOnly(vm, id) {
+				continue
+			}
+			for _, eni := range instance.NetworkInterfaces {
+				if eni.Attachment == nil || aws.Int64Value(eni.Attachment.DeviceIndex) != nic.DeviceIndex {
+					continue
+				}
+				if eni.SourceDestCheck != nil && *eni.SourceDestCheck == *nic.SourceDestCheck {
+					continue
+				}
+				_, err := svc.ModifyNetworkInterfaceAttribute(&ec2.ModifyNetworkInterfaceAttributeInput{
+					NetworkInterfaceId: eni.NetworkInterfaceId,
+					SourceDestCheck:    &ec2.AttributeBooleanValue{Value: nic.SourceDestCheck},
+				})
+				if err != nil {
+					return fmt.Errorf("failed to set source/dest check on %s: %w", aws.StringValue(eni.NetworkInterfaceId), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// recordDryRun stores the outcome of a dry-run launch in the VM status. AWS
+// always answers a dry run with an error; only codes other than the expected
+// DryRunOperation and UnauthorizedOperation are returned to the caller.
+func recordDryRun(vm *v1.Vm, err error) error {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return fmt.Errorf("unexpected dry-run result: %w", err)
+	}
+	switch aerr.Code() {
+	case "DryRunOperation", "UnauthorizedOperation":
+		vm.Status.DryRun = &v1.DryRunStatus{
+			Code:    aerr.Code(),
+			Message: aerr.Message(),
+		}
+		return nil
+	default:
+		return err
 	}
-	input := &ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice(instancesIds)}
+}
 
-	result, err := svc.DescribeInstances(input)
+// GetExistingVM gets the existing EC2 instance details. The described
+// instances are returned for the later steps of the reconcile, so each
+// reconcile describes them only once. Instances AWS no longer returns were
+// terminated and purged, and are recorded as terminated.
+func (c *AwsSession) GetExistingVM(vm *v1.Vm) ([]*ec2.Instance, error) {
+	svc := ec2.New(c.sess)
+
+	instances, err := findInstances(svc, instanceIds(vm))
 	if err != nil {
 		fmt.Printf("Error describing EC2 instance: %v\n", err)
-		return err
+		return nil, err
 	}
+	// Keep the controller-managed progress of in-place updates, the
+	// Elastic IP association and the health checks, which DescribeInstances
+	// does not return
+	previous := make(map[string]v1.InstanceStatus, len(vm.Status.InstanceStatus))
+	for _, instance := range vm.Status.InstanceStatus {
+		previous[instance.InstanceId] = instance
+	}
+	ids := instanceIds(vm)
 	vm.Status.InstanceStatus = []v1.InstanceStatus{}
 	// Store details in VM status
-	for i := range result.Reservations {
-		for j := range result.Reservations[i].Instances {
-			instance := v1.InstanceStatus{
-				InstanceId: *result.Reservations[i].Instances[j].InstanceId,
-				State:      *result.Reservations[i].Instances[j].State.Name,
-			}
-			if result.Reservations[i].Instances[j].PrivateIpAddress != nil {
-				instance.PrivateIpAddresses = *result.Reservations[i].Instances[j].PrivateIpAddress
-			}
-			if result.Reservations[i].Instances[j].PublicIpAddress != nil {
-				instance.PublicIpAddresses = *result.Reservations[i].Instances[j].PublicIpAddress
+	for _, described := range instances {
+		instance := instanceStatus(described)
+		if known, ok := previous[instance.InstanceId]; ok {
+			instance.Update = known.Update
+			instance.AllocationId = known.AllocationId
+			instance.AssociationId = known.AssociationId
+			instance.Health = known.Health
+			delete(previous, instance.InstanceId)
+		}
+		vm.Status.InstanceStatus = append(vm.Status.InstanceStatus, instance)
+	}
+	for _, id := range ids {
+		missing, ok := previous[id]
+		if !ok {
+			continue
+		}
+		if purged(missing) {
+			missing.State = ec2.InstanceStateNameTerminated
+			missing.Update = nil
+		}
+		vm.Status.InstanceStatus = append(vm.Status.InstanceStatus, missing)
+	}
+	return instances, nil
+}
+
+// purged reports whether an instance DescribeInstances did not return is
+// gone, rather than launched too recently to be visible yet.
+func purged(instance v1.InstanceStatus) bool {
+	return instance.LaunchTime == nil || time.Since(instance.LaunchTime.Time) > launchVisibilityDelay
+}
+
+// instanceStatus records the observed state of an EC2 instance.
+func instanceStatus(instance *ec2.Instance) v1.InstanceStatus {
+	status := v1.InstanceStatus{
+		InstanceId:            aws.StringValue(instance.InstanceId),
+		InstanceType:          aws.StringValue(instance.InstanceType),
+		PrivateIpAddresses:    aws.StringValue(instance.PrivateIpAddress),
+		PublicIpAddresses:     aws.StringValue(instance.PublicIpAddress),
+		PrivateDnsName:        aws.StringValue(instance.PrivateDnsName),
+		PublicDnsName:         aws.StringValue(instance.PublicDnsName),
+		ImageId:               aws.StringValue(instance.ImageId),
+		StateTransitionReason: aws.StringValue(instance.StateTransitionReason),
+		SubnetId:              aws.StringValue(instance.SubnetId),
+		Revision:              tagValue(instance.Tags, revisionTagKey),
+		Lifecycle:             aws.StringValue(instance.InstanceLifecycle),
+	}
+	if instance.State != nil {
+		status.State = aws.StringValue(instance.State.Name)
+	}
+	if instance.StateReason != nil {
+		status.StateReason = aws.StringValue(instance.StateReason.Code)
+	}
+	if instance.Placement != nil {
+		status.AvailabilityZone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+	if instance.LaunchTime != nil {
+		launchTime := metav1.NewTime(*instance.LaunchTime)
+		status.LaunchTime = &launchTime
+	}
+	for _, device := range instance.BlockDeviceMappings {
+		if device.Ebs != nil {
+			status.VolumeIds = append(status.VolumeIds, aws.StringValue(device.Ebs.VolumeId))
+		}
+	}
+	for _, eni := range instance.NetworkInterfaces {
+		status.NetworkInterfaceIds = append(status.NetworkInterfaceIds, aws.StringValue(eni.NetworkInterfaceId))
+		for _, address := range eni.PrivateIpAddresses {
+			status.PrivateIps = append(status.PrivateIps, aws.StringValue(address.PrivateIpAddress))
+			if address.Association != nil && address.Association.PublicIp != nil {
+				status.PublicIps = append(status.PublicIps, aws.StringValue(address.Association.PublicIp))
 			}
-			vm.Status.InstanceStatus = append(vm.Status.InstanceStatus, instance)
+		}
+		for _, address := range eni.Ipv6Addresses {
+			status.Ipv6Addresses = append(status.Ipv6Addresses, aws.StringValue(address.Ipv6Address))
+		}
+	}
+	return status
+}
+
+// SyncMetadataOptions re-applies the Vm IMDS settings to every running or
+// stopped instance whose live metadata options have drifted from the spec.
+// The instances are the ones described by GetExistingVM.
+func (c *AwsSession) SyncMetadataOptions(vm *v1.Vm, instances []*ec2.Instance) error {
+	if vm.Spec.MetadataOptions == nil || len(instances) == 0 {
+		return nil
+	}
+	svc := ec2.New(c.sess)
+
+	for _, instance := range instances {
+		state := recordedState(vm, aws.StringValue(instance.InstanceId))
+		if state != ec2.InstanceStateNameRunning && state != ec2.InstanceStateNameStopped {
+			continue
+		}
+		if reportOnly(vm, aws.StringValue(instance.InstanceId)) {
+			continue
+		}
+		if metadataOptionsMatch(vm.Spec.MetadataOptions, instance.MetadataOptions) {
+			continue
+		}
+		opts := metadataOptions(vm.Spec.MetadataOptions)
+		_, err := svc.ModifyInstanceMetadataOptions(&ec2.ModifyInstanceMetadataOptionsInput{
+			InstanceId:              instance.InstanceId,
+			HttpTokens:              opts.HttpTokens,
+			HttpPutResponseHopLimit: opts.HttpPutResponseHopLimit,
+			HttpEndpoint:            opts.HttpEndpoint,
+			InstanceMetadataTags:    opts.InstanceMetadataTags,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to modify metadata options of %s: %w", aws.StringValue(instance.InstanceId), err)
 		}
 	}
 	return nil
 }
 
-// DeleteVM deletes the existing EC2 instance.
-func (c *AwsSession) DeleteVM(vm *v1.Vm) error {
+// recordedState returns the state of the instance in the VM status, which
+// includes the transitions started earlier in the reconcile, or "" when the
+// instance is not recorded.
+func recordedState(vm *v1.Vm, instanceId string) string {
+	for _, instance := range vm.Status.InstanceStatus {
+		if instance.InstanceId == instanceId {
+			return instance.State
+		}
+	}
+	return ""
+}
+
+// instanceIds returns the IDs of the instances recorded in the VM status.
+func instanceIds(vm *v1.Vm) []string {
+	ids := make([]string, len(vm.Status.InstanceStatus))
+	for i, instance := range vm.Status.InstanceStatus {
+		ids[i] = instance.InstanceId
+	}
+	return ids
+}
+
+// findInstances returns the instances with the given IDs that AWS still
+// knows about. A filter, unlike InstanceIds, does not fail on unknown IDs.
+func findInstances(svc *ec2.EC2, ids []string) ([]*ec2.Instance, error) {
+	instances, err := listInstances(svc, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("instance-id"), Values: aws.StringSlice(ids)}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instances %v: %w", ids, err)
+	}
+	return instances, nil
+}
+
+// describeInstances returns the instances with the given IDs.
+func describeInstances(svc *ec2.EC2, ids []string) ([]*ec2.Instance, error) {
+	instances, err := listInstances(svc, &ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice(ids)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instances %v: %w", ids, err)
+	}
+	return instances, nil
+}
+
+// primaryNetworkInterface returns the network interface of the instance at
+// device index 0, or nil when it has none.
+func primaryNetworkInterface(instance *ec2.Instance) *ec2.InstanceNetworkInterface {
+	for _, eni := range instance.NetworkInterfaces {
+		if eni.Attachment != nil && aws.Int64Value(eni.Attachment.DeviceIndex) == 0 {
+			return eni
+		}
+	}
+	return nil
+}
+
+// listInstances returns every instance matching the input, flattened across
+// reservations and pages.
+func listInstances(svc *ec2.EC2, input *ec2.DescribeInstancesInput) ([]*ec2.Instance, error) {
+	var instances []*ec2.Instance
+	err := svc.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+		return true
+	})
+	return instances, err
+}
+
+// DeleteVM terminates the instances of the Vm that are not terminating yet
+// and reports whether every instance has terminated. The caller refreshes
+// the instance states with FindOwnedInstances before each call.
+func (c *AwsSession) DeleteVM(vm *v1.Vm) (bool, error) {
 	svc := ec2.New(c.sess)
 
-	instancesIds := make([]string, len(vm.Status.InstanceStatus))
-	for i, id := range vm.Status.InstanceStatus {
-		instancesIds[i] = id.InstanceId
+	done := true
+	var instancesIds []string
+	for _, instance := range vm.Status.InstanceStatus {
+		switch instance.State {
+		case ec2.InstanceStateNameTerminated:
+			// Already gone
+		case ec2.InstanceStateNameShuttingDown:
+			done = false
+		default:
+			done = false
+			instancesIds = append(instancesIds, instance.InstanceId)
+		}
+	}
+	if len(instancesIds) == 0 {
+		return done, nil
 	}
 	// Specifying instance ID for termination
 	terminateInput := &ec2.TerminateInstancesInput{
 		InstanceIds: aws.StringSlice(instancesIds),
 	}
 
-	_, err := svc.TerminateInstances(terminateInput)
+	output, err := svc.TerminateInstances(terminateInput)
 	if err != nil {
-		return fmt.Errorf("error terminating EC2 instance: %v", err)
+		return false, fmt.Errorf("failed to terminate instances: %w", err)
 
 	}
+	recordStateChanges(vm, output.TerminatingInstances)
 
 	fmt.Printf("Terminated EC2 instance with ID: %s\n", instancesIds)
 
+	return false, nil
+}
+
+// FindOwnedInstances refreshes the VM status with every instance recorded in
+// it or carrying the ownership tags of the Vm. Instances AWS no longer knows
+// about are dropped.
+func (c *AwsSession) FindOwnedInstances(vm *v1.Vm) error {
+	svc := ec2.New(c.sess)
+
+	ids := instanceIds(vm)
+	tagged, err := listInstances(svc, &ec2.DescribeInstancesInput{Filters: c.ownedInstanceFilters(vm)})
+	if err != nil {
+		return fmt.Errorf("failed to discover instances owned by %s/%s: %w", vm.Namespace, vm.Name, err)
+	}
+	for _, instance := range tagged {
+		ids = appendUnique(ids, aws.StringValue(instance.InstanceId))
+	}
+
+	vm.Status.InstanceStatus = nil
+	if len(ids) == 0 {
+		return nil
+	}
+	instances, err := findInstances(svc, ids)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		vm.Status.InstanceStatus = append(vm.Status.InstanceStatus, instanceStatus(instance))
+	}
 	return nil
 }
@@ -0,0 +1,366 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// remediationCooldown is how long an instance is given to recover after a
+// repair before it is repaired again.
+const remediationCooldown = 10 * time.Minute
+
+// Scheduled events resolved by stopping and starting the instance.
+var stopStartEventCodes = []string{
+	ec2.EventCodeInstanceRetirement,
+	ec2.EventCodeInstanceStop,
+}
+
+// Remediation is a repair applied to an unhealthy instance.
+type Remediation struct {
+	InstanceId string
+	Action     string
+	Reason     string
+}
+
+// passingStatusChecks reports, per instance ID, whether both the system and
+// the instance reachability checks of a running instance are ok.
+func passingStatusChecks(svc *ec2.EC2, ids []string) (map[string]bool, error) {
+	passing := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return passing, nil
+	}
+
+	input := &ec2.DescribeInstanceStatusInput{InstanceIds: aws.StringSlice(ids)}
+	err := svc.DescribeInstanceStatusPages(input, func(page *ec2.DescribeInstanceStatusOutput, lastPage bool) bool {
+		for _, status := range page.InstanceStatuses {
+			passing[aws.StringValue(status.InstanceId)] =
+				status.InstanceStatus != nil && aws.StringValue(status.InstanceStatus.Status) == ec2.SummaryStatusOk &&
+					status.SystemStatus != nil && aws.StringValue(status.SystemStatus.Status) == ec2.SummaryStatusOk
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe status of instances %v: %w", ids, err)
+	}
+	return passing, nil
+}
+
+// SyncHealth records the status checks and pending scheduled events of the
+// live instances in the Vm status.
+func (c *AwsSession) SyncHealth(vm *v1.Vm) error {
+	live := liveInstances(vm)
+	if len(live) == 0 {
+		return nil
+	}
+	svc := ec2.New(c.sess)
+
+	ids := make([]string, 0, len(live))
+	for _, instance := range live {
+		ids = append(ids, instance.InstanceId)
+	}
+	statuses := make(map[string]*ec2.InstanceStatus, len(ids))
+	input := &ec2.DescribeInstanceStatusInput{
+		InstanceIds: aws.StringSlice(ids),
+		// Stopped instances can still have scheduled events
+		IncludeAllInstances: aws.Bool(true),
+	}
+	err := svc.DescribeInstanceStatusPages(input, func(page *ec2.DescribeInstanceStatusOutput, lastPage bool) bool {
+		for _, status := range page.InstanceStatuses {
+			statuses[aws.StringValue(status.InstanceId)] = status
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe status of instances %v: %w", ids, err)
+	}
+	volumes, err := volumeStatuses(svc, live)
+	if err != nil {
+		return err
+	}
+
+	for i := range vm.Status.InstanceStatus {
+		instance := &vm.Status.InstanceStatus[i]
+		status, ok := statuses[instance.InstanceId]
+		if !ok {
+			continue
+		}
+		health := &v1.InstanceHealth{
+			SystemStatus:   summaryStatus(status.SystemStatus),
+			InstanceStatus: summaryStatus(status.InstanceStatus),
+			EbsStatus:      ebsStatus(volumes, instance.VolumeIds),
+			Events:         scheduledEvents(status.Events),
+		}
+		if instance.Health != nil {
+			health.LastRemediation = instance.Health.LastRemediation
+			health.LastRemediationTime = instance.Health.LastRemediationTime
+		}
+		instance.Health = health
+	}
+	return nil
+}
+
+// Health summarizes the status checks and scheduled events of a Vm.
+type Health struct {
+	// Impaired describes the failing checks of running instances.
+	Impaired []string
+	// Initializing lists the running instances whose checks have no result yet.
+	Initializing []string
+	// Events describes the pending scheduled events.
+	Events []string
+}
+
+// CheckHealth summarizes the health recorded by SyncHealth.
+func CheckHealth(vm *v1.Vm) Health {
+	var h Health
+	for _, instance := range vm.Status.InstanceStatus {
+		running := instance.State == ec2.InstanceStateNameRunning
+		health := instance.Health
+		if health == nil {
+			if running {
+				h.Initializing = append(h.Initializing, instance.InstanceId)
+			}
+			continue
+		}
+		for _, event := range health.Events {
+			message := fmt.Sprintf("%s on %s", event.Code, instance.InstanceId)
+			if event.NotBefore != nil {
+				message += " from " + event.NotBefore.UTC().Format(time.RFC3339)
+			}
+			h.Events = append(h.Events, message)
+		}
+		if !running {
+			continue
+		}
+		pending := false
+		for _, check := range []struct{ name, status string }{
+			{"system", health.SystemStatus},
+			{"instance", health.InstanceStatus},
+			{"ebs", health.EbsStatus},
+		} {
+			switch check.status {
+			case ec2.SummaryStatusImpaired:
+				h.Impaired = append(h.Impaired, fmt.Sprintf("%s %s check impaired", instance.InstanceId, check.name))
+			case ec2.SummaryStatusInitializing, ec2.SummaryStatusInsufficientData:
+				pending = true
+			}
+		}
+		if pending {
+			h.Initializing = append(h.Initializing, instance.InstanceId)
+		}
+	}
+	return h
+}
+
+// RemediateHealth repairs the unhealthy instances as selected by
+// spec.healthRemediation and advances the stop-start cycles in progress.
+// Instances are rebooted when their instance check fails, and stopped and
+// started when their system check fails or they are scheduled for retirement.
+// Repairs only start within the budget left by unavailableBudget. It returns
+// the repairs it started.
+func (c *AwsSession) RemediateHealth(vm *v1.Vm) ([]Remediation, error) {
+	opts := vm.Spec.HealthRemediation
+	if opts == nil || vm.Spec.DryRun {
+		return nil, nil
+	}
+	svc := ec2.New(c.sess)
+
+	for i := range vm.Status.InstanceStatus {
+		instance := &vm.Status.InstanceStatus[i]
+		if instance.Update == nil || instance.Update.Type != v1.InstanceUpdateStopStart {
+			continue
+		}
+		if instance.Update.Phase == v1.InstanceUpdateFailed {
+			// Try again once the instance had time to recover on its own
+			if !coolingDown(instance) {
+				instance.Update = nil
+			}
+			continue
+		}
+		if err := advanceStopStart(svc, vm, instance); err != nil {
+			return nil, err
+		}
+	}
+
+	if !WantsRunning(vm) {
+		return nil, nil
+	}
+	// Shared with resizes and any other instance that is out of service
+	budget := unavailableBudget(vm)
+	var remediations []Remediation
+	for i := range vm.Status.InstanceStatus {
+		instance := &vm.Status.InstanceStatus[i]
+		if budget <= 0 {
+			break
+		}
+		if instance.State != ec2.InstanceStateNameRunning || instance.Health == nil || instance.Update != nil {
+			continue
+		}
+		if coolingDown(instance) || reportOnly(vm, instance.InstanceId) {
+			continue
+		}
+
+		health := instance.Health
+		var action, reason string
+		switch {
+		case opts.StopStartOnSystemCheckFailure && health.SystemStatus == ec2.SummaryStatusImpaired:
+			action, reason = v1.InstanceUpdateStopStart, "system status check impaired"
+		case opts.StopStartOnRetirement && stopStartEvent(health) != "":
+			action, reason = v1.InstanceUpdateStopStart, stopStartEvent(health)+" scheduled"
+		case opts.RebootOnInstanceCheckFailure && health.InstanceStatus == ec2.SummaryStatusImpaired:
+			action, reason = v1.InstanceRemediationReboot, "instance status check impaired"
+		default:
+			continue
+		}
+
+		switch action {
+		case v1.InstanceUpdateStopStart:
+			_, err := svc.StopInstances(&ec2.StopInstancesInput{InstanceIds: []*string{aws.String(instance.InstanceId)}})
+			if err != nil {
+				// Instances with an instance store root cannot be stopped
+				instance.Update = &v1.InstanceUpdate{Type: v1.InstanceUpdateStopStart, Phase: v1.InstanceUpdateFailed, Message: err.Error()}
+			} else {
+				instance.State = ec2.InstanceStateNameStopping
+				instance.Update = &v1.InstanceUpdate{Type: v1.InstanceUpdateStopStart, Phase: v1.InstanceUpdateStopping}
+			}
+		case v1.InstanceRemediationReboot:
+			_, err := svc.RebootInstances(&ec2.RebootInstancesInput{InstanceIds: []*string{aws.String(instance.InstanceId)}})
+			if err != nil {
+				return remediations, fmt.Errorf("failed to reboot instance %s: %w", instance.InstanceId, err)
+			}
+		}
+		now := metav1.Now()
+		health.LastRemediation = action
+		health.LastRemediationTime = &now
+		remediations = append(remediations, Remediation{InstanceId: instance.InstanceId, Action: action, Reason: reason})
+		budget--
+	}
+	return remediations, nil
+}
+
+// advanceStopStart starts an instance stopped for remediation once it has
+// stopped, and completes the cycle once it is running again.
+func advanceStopStart(svc *ec2.EC2, vm *v1.Vm, instance *v1.InstanceStatus) error {
+	update := instance.Update
+	switch {
+	case instance.State == ec2.InstanceStateNameStopped && update.Phase == v1.InstanceUpdateStopping:
+		if !WantsRunning(vm) {
+			instance.Update = nil
+			return nil
+		}
+		_, err := svc.StartInstances(&ec2.StartInstancesInput{InstanceIds: []*string{aws.String(instance.InstanceId)}})
+		if err != nil {
+			return fmt.Errorf("failed to start instance %s after stop-start: %w", instance.InstanceId, err)
+		}
+		instance.State = ec2.InstanceStateNamePending
+		update.Phase = v1.InstanceUpdateStarting
+	case instance.State == ec2.InstanceStateNameRunning && update.Phase == v1.InstanceUpdateStarting:
+		instance.Update = nil
+	case instance.State == ec2.InstanceStateNameShuttingDown || instance.State == ec2.InstanceStateNameTerminated:
+		instance.Update = nil
+	}
+	return nil
+}
+
+// coolingDown reports whether the instance was repaired too recently to be
+// repaired again.
+func coolingDown(instance *v1.InstanceStatus) bool {
+	health := instance.Health
+	if health == nil || health.LastRemediationTime == nil {
+		return false
+	}
+	return time.Since(health.LastRemediationTime.Time) < remediationCooldown
+}
+
+// stopStartEvent returns the code of the first pending event that a
+// stop-start resolves, or "" when there is none.
+func stopStartEvent(health *v1.InstanceHealth) string {
+	for _, event := range health.Events {
+		if containsString(stopStartEventCodes, event.Code) {
+			return event.Code
+		}
+	}
+	return ""
+}
+
+// scheduledEvents converts the pending events of an instance, skipping the
+// ones AWS reports as completed or canceled.
+func scheduledEvents(events []*ec2.InstanceStatusEvent) []v1.ScheduledEvent {
+	var out []v1.ScheduledEvent
+	for _, event := range events {
+		description := aws.StringValue(event.Description)
+		if strings.HasPrefix(description, "[Completed]") || strings.HasPrefix(description, "[Canceled]") {
+			continue
+		}
+		out = append(out, v1.ScheduledEvent{
+			Code:        aws.StringValue(event.Code),
+			Description: description,
+			NotBefore:   optionalTime(event.NotBefore),
+			NotAfter:    optionalTime(event.NotAfter),
+		})
+	}
+	return out
+}
+
+func summaryStatus(summary *ec2.InstanceStatusSummary) string {
+	if summary == nil {
+		return ""
+	}
+	return aws.StringValue(summary.Status)
+}
+
+// volumeStatuses returns the status of the volumes attached to the
+// instances, keyed by volume ID.
+func volumeStatuses(svc *ec2.EC2, instances []v1.InstanceStatus) (map[string]string, error) {
+	var ids []string
+	for _, instance := range instances {
+		ids = append(ids, instance.VolumeIds...)
+	}
+	statuses := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return statuses, nil
+	}
+
+	input := &ec2.DescribeVolumeStatusInput{VolumeIds: aws.StringSlice(ids)}
+	err := svc.DescribeVolumeStatusPages(input, func(page *ec2.DescribeVolumeStatusOutput, lastPage bool) bool {
+		for _, volume := range page.VolumeStatuses {
+			if volume.VolumeStatus != nil {
+				statuses[aws.StringValue(volume.VolumeId)] = aws.StringValue(volume.VolumeStatus.Status)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe status of volumes %v: %w", ids, err)
+	}
+	return statuses, nil
+}
+
+// ebsStatus summarizes the status of the given volumes as the worst of them.
+func ebsStatus(statuses map[string]string, volumeIds []string) string {
+	severity := map[string]int{
+		ec2.VolumeStatusInfoStatusOk:               1,
+		ec2.VolumeStatusInfoStatusInsufficientData: 2,
+		ec2.VolumeStatusInfoStatusImpaired:         3,
+	}
+	worst := ""
+	for _, id := range volumeIds {
+		if status := statuses[id]; severity[status] > severity[worst] {
+			worst = status
+		}
+	}
+	return worst
+}
+
+func optionalTime(t *time.Time) *metav1.Time {
+	if t == nil {
+		return nil
+	}
+	mt := metav1.NewTime(*t)
+	return &mt
+}
@@ -0,0 +1,112 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// ResolveImage resolves spec.imageSelector to an AMI and records it in the VM
+// status. A Pinned selector is only resolved again once it changes, so new
+// instances keep matching the existing ones.
+func (c *AwsSession) ResolveImage(vm *v1.Vm) error {
+	selector := vm.Spec.ImageSelector
+	if selector == nil || vm.Spec.ImageId != "" {
+		vm.Status.ResolvedImage = nil
+		return nil
+	}
+
+	description := describeImageSelector(selector)
+	resolved := vm.Status.ResolvedImage
+	if resolved != nil && resolved.Selector == description && selector.UpdatePolicy != v1.ImageUpdateRollout {
+		return nil
+	}
+
+	var imageId string
+	var err error
+	if selector.SsmParameter != "" {
+		imageId, err = c.imageFromParameter(selector.SsmParameter)
+	} else {
+		imageId, err = c.newestImage(selector)
+	}
+	if err != nil {
+		return err
+	}
+	vm.Status.ResolvedImage = &v1.ResolvedImage{ImageId: imageId, Selector: description}
+	return nil
+}
+
+// imageId returns the AMI instances are launched from: spec.imageId, or the
+// AMI the image selector resolved to.
+func imageId(vm *v1.Vm) string {
+	if vm.Spec.ImageId == "" && vm.Status.ResolvedImage != nil {
+		return vm.Status.ResolvedImage.ImageId
+	}
+	return vm.Spec.ImageId
+}
+
+// describeImageSelector renders the selector fields that determine the AMI.
+func describeImageSelector(selector *v1.ImageSelector) string {
+	if selector.SsmParameter != "" {
+		return "ssm:" + selector.SsmParameter
+	}
+	return fmt.Sprintf("name=%s,owners=%s,architecture=%s",
+		selector.Name, strings.Join(selector.Owners, "+"), selector.Architecture)
+}
+
+// imageFromParameter reads an AMI ID from an SSM parameter.
+func (c *AwsSession) imageFromParameter(name string) (string, error) {
+	svc := ssm.New(c.sess)
+
+	output, err := svc.GetParameter(&ssm.GetParameterInput{Name: aws.String(name)})
+	if err != nil {
+		return "", fmt.Errorf("failed to read image parameter %s: %w", name, err)
+	}
+	value := aws.StringValue(output.Parameter.Value)
+	if !strings.HasPrefix(value, "ami-") {
+		return "", fmt.Errorf("image parameter %s does not hold an AMI ID: %q", name, value)
+	}
+	return value, nil
+}
+
+// newestImage returns the most recently created available image matching the
+// selector filters.
+func (c *AwsSession) newestImage(selector *v1.ImageSelector) (string, error) {
+	if selector.Name == "" && len(selector.Owners) == 0 {
+		return "", fmt.Errorf("image selector needs an SSM parameter, a name or owners")
+	}
+	svc := ec2.New(c.sess)
+
+	input := &ec2.DescribeImagesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("state"), Values: aws.StringSlice([]string{ec2.ImageStateAvailable})},
+		},
+	}
+	if selector.Name != "" {
+		input.Filters = append(input.Filters, &ec2.Filter{Name: aws.String("name"), Values: aws.StringSlice([]string{selector.Name})})
+	}
+	if selector.Architecture != "" {
+		input.Filters = append(input.Filters, &ec2.Filter{Name: aws.String("architecture"), Values: aws.StringSlice([]string{selector.Architecture})})
+	}
+	if len(selector.Owners) > 0 {
+		input.Owners = aws.StringSlice(selector.Owners)
+	}
+
+	output, err := svc.DescribeImages(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up images for %s: %w", describeImageSelector(selector), err)
+	}
+	if len(output.Images) == 0 {
+		return "", fmt.Errorf("no image matches %s", describeImageSelector(selector))
+	}
+	// CreationDate is an ISO 8601 timestamp, so it sorts lexically
+	sort.Slice(output.Images, func(i, j int) bool {
+		return aws.StringValue(output.Images[i].CreationDate) > aws.StringValue(output.Images[j].CreationDate)
+	})
+	return aws.StringValue(output.Images[0].ImageId), nil
+}
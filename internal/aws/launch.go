@@ -0,0 +1,240 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// newRunInstancesInput maps the Vm spec onto a RunInstances request into the
+// given subnet that applies the given tags to every launched resource.
+func newRunInstancesInput(vm *v1.Vm, subnetId string, tags map[string]string) *ec2.RunInstancesInput {
+	runInput := &ec2.RunInstancesInput{
+		ImageId:      aws.String(imageId(vm)),
+		InstanceType: aws.String(vm.Spec.InstanceType),
+		MinCount:     aws.Int64(int64(vm.Spec.MinCount)),
+		MaxCount:     aws.Int64(int64(vm.Spec.MaxCount)),
+		KeyName:      aws.String(vm.Spec.KeyName),
+		SubnetId:     aws.String(subnetId),
+	}
+	if vm.Spec.LaunchTemplate != nil {
+		// Only the fields set on the Vm override the launch template
+		runInput.ImageId = optionalString(imageId(vm))
+		runInput.InstanceType = optionalString(vm.Spec.InstanceType)
+		runInput.KeyName = optionalString(vm.Spec.KeyName)
+		runInput.SubnetId = optionalString(subnetId)
+	}
+
+	if groupIds := securityGroupIds(vm); len(groupIds) > 0 {
+		runInput.SecurityGroupIds = aws.StringSlice(groupIds)
+	}
+	if vm.Spec.UserData != "" {
+		runInput.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(vm.Spec.UserData)))
+	}
+	if vm.Spec.IamInstanceProfile != "" {
+		runInput.IamInstanceProfile = iamInstanceProfile(vm.Spec.IamInstanceProfile)
+	}
+	if len(vm.Spec.BlockDeviceMappings) > 0 {
+		runInput.BlockDeviceMappings = blockDeviceMappings(vm.Spec.BlockDeviceMappings)
+	}
+	if len(vm.Spec.NetworkInterfaces) > 0 {
+		// RunInstances rejects instance-level subnet and security groups
+		// when network interfaces are given, so they move onto the interfaces.
+		runInput.NetworkInterfaces = networkInterfaces(vm, subnetId)
+		runInput.SubnetId = nil
+		runInput.SecurityGroupIds = nil
+	}
+	if vm.Spec.MetadataOptions != nil {
+		runInput.MetadataOptions = metadataOptions(vm.Spec.MetadataOptions)
+	}
+	if len(tags) > 0 {
+		runInput.TagSpecifications = tagSpecifications(tags)
+	}
+	if vm.Spec.InstanceMarketOptions != nil {
+		runInput.InstanceMarketOptions = instanceMarketOptions(vm.Spec.InstanceMarketOptions)
+	}
+	if vm.Spec.Placement != nil {
+		runInput.Placement = placement(vm.Spec.Placement)
+	}
+	if vm.Spec.Hibernation {
+		runInput.HibernationOptions = &ec2.HibernationOptionsRequest{Configured: aws.Bool(true)}
+	}
+
+	return runInput
+}
+
+// LaunchToken derives a deterministic RunInstances client token from the Vm
+// UID, its generation and the number of launches it completed, so retrying
+// the same launch is idempotent while every later launch gets a token of its own.
+func LaunchToken(vm *v1.Vm) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d/%d", vm.UID, vm.Generation, vm.Status.Launches)))
+	// A hex encoded SHA-256 is 64 characters, the maximum AWS accepts
+	return fmt.Sprintf("%x", sum)
+}
+
+// Revision hashes the spec fields that cannot be changed on a running
+// instance. Instances launched from another revision are replaced.
+func Revision(vm *v1.Vm) string {
+	fields := []string{
+		imageId(vm),
+		vm.Spec.UserData,
+		vm.Spec.SubnetId,
+		vm.Spec.KeyName,
+	}
+	// Optional fields only take part once set, so existing instances keep
+	// their revision
+	if p := vm.Spec.Placement; p != nil {
+		fields = append(fields, "placement", p.AvailabilityZone, p.Tenancy, p.GroupName,
+			strconv.FormatInt(p.PartitionNumber, 10), p.HostId)
+	}
+	if t := vm.Spec.LaunchTemplate; t != nil {
+		fields = append(fields, "launchTemplate", t.LaunchTemplateId, t.LaunchTemplateName, t.Version)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return fmt.Sprintf("%x", sum[:5])
+}
+
+// optionalString returns nil for an empty string, leaving the field unset.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// iamInstanceProfile references an instance profile by ARN or by name.
+func iamInstanceProfile(profile string) *ec2.IamInstanceProfileSpecification {
+	if strings.HasPrefix(profile, "arn:") {
+		return &ec2.IamInstanceProfileSpecification{Arn: aws.String(profile)}
+	}
+	return &ec2.IamInstanceProfileSpecification{Name: aws.String(profile)}
+}
+
+// blockDeviceMappings converts the Vm block devices to their EC2 form, leaving
+// unset fields nil so AWS applies the AMI and volume type defaults.
+func blockDeviceMappings(devices []v1.BlockDeviceMapping) []*ec2.BlockDeviceMapping {
+	mappings := make([]*ec2.BlockDeviceMapping, 0, len(devices))
+	for _, device := range devices {
+		mapping := &ec2.BlockDeviceMapping{DeviceName: aws.String(device.DeviceName)}
+		if device.Ebs != nil {
+			mapping.Ebs = ebsBlockDevice(device.Ebs)
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings
+}
+
+func ebsBlockDevice(ebs *v1.EbsBlockDevice) *ec2.EbsBlockDevice {
+	out := &ec2.EbsBlockDevice{
+		Encrypted:           ebs.Encrypted,
+		DeleteOnTermination: ebs.DeleteOnTermination,
+	}
+	if ebs.VolumeSize > 0 {
+		out.VolumeSize = aws.Int64(ebs.VolumeSize)
+	}
+	if ebs.VolumeType != "" {
+		out.VolumeType = aws.String(ebs.VolumeType)
+	}
+	if ebs.Iops > 0 {
+		out.Iops = aws.Int64(ebs.Iops)
+	}
+	if ebs.Throughput > 0 {
+		out.Throughput = aws.Int64(ebs.Throughput)
+	}
+	if ebs.KmsKeyId != "" {
+		out.KmsKeyId = aws.String(ebs.KmsKeyId)
+	}
+	if ebs.SnapshotId != "" {
+		out.SnapshotId = aws.String(ebs.SnapshotId)
+	}
+	return out
+}
+
+// networkInterfaces converts the Vm network interfaces to their EC2 form. The
+// primary interface inherits the launch subnet and the Vm security groups when
+// it does not set its own.
+func networkInterfaces(vm *v1.Vm, defaultSubnetId string) []*ec2.InstanceNetworkInterfaceSpecification {
+	specs := make([]*ec2.InstanceNetworkInterfaceSpecification, 0, len(vm.Spec.NetworkInterfaces))
+	for _, nic := range vm.Spec.NetworkInterfaces {
+		spec := &ec2.InstanceNetworkInterfaceSpecification{
+			DeviceIndex:              aws.Int64(nic.DeviceIndex),
+			AssociatePublicIpAddress: nic.AssociatePublicIpAddress,
+		}
+
+		nicSubnetId, nicGroupIds := nic.SubnetId, nic.SecurityGroupIds
+		if nic.DeviceIndex == 0 {
+			if nicSubnetId == "" {
+				nicSubnetId = defaultSubnetId
+			}
+			if len(nicGroupIds) == 0 {
+				nicGroupIds = securityGroupIds(vm)
+			}
+		}
+		if nicSubnetId != "" {
+			spec.SubnetId = aws.String(nicSubnetId)
+		}
+		if len(nicGroupIds) > 0 {
+			spec.Groups = aws.StringSlice(nicGroupIds)
+		}
+
+		for _, ip := range nic.SecondaryPrivateIpAddresses {
+			spec.PrivateIpAddresses = append(spec.PrivateIpAddresses, &ec2.PrivateIpAddressSpecification{
+				PrivateIpAddress: aws.String(ip),
+				Primary:          aws.Bool(false),
+			})
+		}
+		if nic.SecondaryPrivateIpAddressCount > 0 {
+			spec.SecondaryPrivateIpAddressCount = aws.Int64(nic.SecondaryPrivateIpAddressCount)
+		}
+		if nic.Ipv6AddressCount > 0 {
+			spec.Ipv6AddressCount = aws.Int64(nic.Ipv6AddressCount)
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// metadataOptions converts the Vm IMDS settings to their launch form.
+func metadataOptions(opts *v1.MetadataOptions) *ec2.InstanceMetadataOptionsRequest {
+	req := &ec2.InstanceMetadataOptionsRequest{}
+	if opts.HttpTokens != "" {
+		req.HttpTokens = aws.String(opts.HttpTokens)
+	}
+	if opts.HttpPutResponseHopLimit > 0 {
+		req.HttpPutResponseHopLimit = aws.Int64(opts.HttpPutResponseHopLimit)
+	}
+	if opts.HttpEndpoint != "" {
+		req.HttpEndpoint = aws.String(opts.HttpEndpoint)
+	}
+	if opts.InstanceMetadataTags != "" {
+		req.InstanceMetadataTags = aws.String(opts.InstanceMetadataTags)
+	}
+	return req
+}
+
+// metadataOptionsMatch reports whether the live IMDS settings of an instance
+// satisfy every field set in the Vm spec.
+func metadataOptionsMatch(opts *v1.MetadataOptions, live *ec2.InstanceMetadataOptionsResponse) bool {
+	if live == nil {
+		return false
+	}
+	if opts.HttpTokens != "" && opts.HttpTokens != aws.StringValue(live.HttpTokens) {
+		return false
+	}
+	if opts.HttpPutResponseHopLimit > 0 && opts.HttpPutResponseHopLimit != aws.Int64Value(live.HttpPutResponseHopLimit) {
+		return false
+	}
+	if opts.HttpEndpoint != "" && opts.HttpEndpoint != aws.StringValue(live.HttpEndpoint) {
+		return false
+	}
+	if opts.InstanceMetadataTags != "" && opts.InstanceMetadataTags != aws.StringValue(live.InstanceMetadataTags) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package aws
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// launchTemplateVersion maps the Vm template version onto the form EC2
+// expects, where Latest and Default are written $Latest and $Default.
+func launchTemplateVersion(version string) string {
+	switch version {
+	case "", "Default":
+		return "$Default"
+	case "Latest":
+		return "$Latest"
+	}
+	return version
+}
+
+// resolveLaunchTemplate looks up the version number the Vm launch template
+// reference currently points to, so that every instance of a launch uses the
+// same version even if the template changes in the meantime.
+func resolveLaunchTemplate(svc *ec2.EC2, ref *v1.LaunchTemplateReference) (*ec2.LaunchTemplateSpecification, error) {
+	input := &ec2.DescribeLaunchTemplateVersionsInput{
+		Versions: aws.StringSlice([]string{launchTemplateVersion(ref.Version)}),
+	}
+	name := ref.LaunchTemplateId
+	if ref.LaunchTemplateId != "" {
+		input.LaunchTemplateId = aws.String(ref.LaunchTemplateId)
+	} else {
+		name = ref.LaunchTemplateName
+		input.LaunchTemplateName = aws.String(ref.LaunchTemplateName)
+	}
+
+	output, err := svc.DescribeLaunchTemplateVersions(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve launch template %s version %s: %w", name, ref.Version, err)
+	}
+	if len(output.LaunchTemplateVersions) == 0 {
+		return nil, fmt.Errorf("launch template %s has no version %s", name, ref.Version)
+	}
+	version := output.LaunchTemplateVersions[0]
+	return &ec2.LaunchTemplateSpecification{
+		LaunchTemplateId: version.LaunchTemplateId,
+		Version:          aws.String(strconv.FormatInt(aws.Int64Value(version.VersionNumber), 10)),
+	}, nil
+}
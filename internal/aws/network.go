@@ -0,0 +1,130 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// ResolveNetwork resolves the subnet and security group selectors of the Vm
+// and records the result in the VM status. Selectors are ignored when the
+// spec lists the subnets or security groups explicitly.
+func (c *AwsSession) ResolveNetwork(vm *v1.Vm) error {
+	svc := ec2.New(c.sess)
+
+	vm.Status.ResolvedSubnetId = ""
+	if vm.Spec.SubnetSelector != nil && vm.Spec.SubnetId == "" && len(vm.Spec.SubnetIds) == 0 {
+		subnetId, err := selectSubnet(svc, vm.Spec.SubnetSelector)
+		if err != nil {
+			return err
+		}
+		vm.Status.ResolvedSubnetId = subnetId
+	}
+
+	vm.Status.ResolvedSecurityGroupIds = nil
+	if vm.Spec.SecurityGroupSelector != nil && len(vm.Spec.SecurityGroupIds) == 0 {
+		groupIds, err := selectSecurityGroups(svc, vm.Spec.SecurityGroupSelector)
+		if err != nil {
+			return err
+		}
+		vm.Status.ResolvedSecurityGroupIds = groupIds
+	}
+	return nil
+}
+
+// subnetId returns the subnet instances are launched into when the Vm does
+// not spread over several subnets.
+func subnetId(vm *v1.Vm) string {
+	if vm.Spec.SubnetId == "" {
+		return vm.Status.ResolvedSubnetId
+	}
+	return vm.Spec.SubnetId
+}
+
+// securityGroupIds returns the security groups of the primary network interface.
+func securityGroupIds(vm *v1.Vm) []string {
+	if len(vm.Spec.SecurityGroupIds) == 0 {
+		return vm.Status.ResolvedSecurityGroupIds
+	}
+	return vm.Spec.SecurityGroupIds
+}
+
+// selectorFilters converts a resource selector to DescribeSubnets and
+// DescribeSecurityGroups filters.
+func selectorFilters(selector v1.ResourceSelector) []*ec2.Filter {
+	var filters []*ec2.Filter
+	for _, key := range sortedKeys(selector.Tags) {
+		if value := selector.Tags[key]; value != "" {
+			filters = append(filters, &ec2.Filter{Name: aws.String("tag:" + key), Values: aws.StringSlice([]string{value})})
+		} else {
+			filters = append(filters, &ec2.Filter{Name: aws.String("tag-key"), Values: aws.StringSlice([]string{key})})
+		}
+	}
+	if selector.VpcId != "" {
+		filters = append(filters, &ec2.Filter{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{selector.VpcId})})
+	}
+	return filters
+}
+
+// selectSubnet returns the matching subnet with the most free IP addresses,
+// preferring the subnets in the selector availability zone.
+func selectSubnet(svc *ec2.EC2, selector *v1.SubnetSelector) (string, error) {
+	input := &ec2.DescribeSubnetsInput{
+		Filters: append(selectorFilters(selector.ResourceSelector),
+			&ec2.Filter{Name: aws.String("state"), Values: aws.StringSlice([]string{ec2.SubnetStateAvailable})}),
+	}
+	var subnets []*ec2.Subnet
+	err := svc.DescribeSubnetsPages(input, func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+		subnets = append(subnets, page.Subnets...)
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up subnets: %w", err)
+	}
+	if len(subnets) == 0 {
+		return "", fmt.Errorf("no available subnet matches the subnet selector")
+	}
+
+	if selector.AvailabilityZone != "" {
+		var inZone []*ec2.Subnet
+		for _, subnet := range subnets {
+			if aws.StringValue(subnet.AvailabilityZone) == selector.AvailabilityZone {
+				inZone = append(inZone, subnet)
+			}
+		}
+		if len(inZone) > 0 {
+			subnets = inZone
+		}
+	}
+	sort.Slice(subnets, func(i, j int) bool {
+		free, otherFree := aws.Int64Value(subnets[i].AvailableIpAddressCount), aws.Int64Value(subnets[j].AvailableIpAddressCount)
+		if free != otherFree {
+			return free > otherFree
+		}
+		return aws.StringValue(subnets[i].SubnetId) < aws.StringValue(subnets[j].SubnetId)
+	})
+	return aws.StringValue(subnets[0].SubnetId), nil
+}
+
+// selectSecurityGroups returns the IDs of every matching security group.
+func selectSecurityGroups(svc *ec2.EC2, selector *v1.ResourceSelector) ([]string, error) {
+	input := &ec2.DescribeSecurityGroupsInput{Filters: selectorFilters(*selector)}
+	var groupIds []string
+	err := svc.DescribeSecurityGroupsPages(input, func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+		for _, group := range page.SecurityGroups {
+			groupIds = append(groupIds, aws.StringValue(group.GroupId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up security groups: %w", err)
+	}
+	if len(groupIds) == 0 {
+		return nil, fmt.Errorf("no security group matches the security group selector")
+	}
+	sort.Strings(groupIds)
+	return groupIds, nil
+}
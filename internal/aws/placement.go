@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// placement converts the Vm placement to its launch form.
+func placement(p *v1.Placement) *ec2.Placement {
+	out := &ec2.Placement{}
+	if p.AvailabilityZone != "" {
+		out.AvailabilityZone = aws.String(p.AvailabilityZone)
+	}
+	if p.Tenancy != "" {
+		out.Tenancy = aws.String(p.Tenancy)
+	}
+	if p.GroupName != "" {
+		out.GroupName = aws.String(p.GroupName)
+	}
+	if p.PartitionNumber > 0 {
+		out.PartitionNumber = aws.Int64(p.PartitionNumber)
+	}
+	if p.HostId != "" {
+		out.HostId = aws.String(p.HostId)
+	}
+	return out
+}
+
+// launchBatch is a single RunInstances call of a launch.
+type launchBatch struct {
+	subnetId string
+	minCount int64
+	maxCount int64
+}
+
+// launchBatches splits a launch of between minCount and maxCount instances
+// across the Vm subnets. Each instance goes to the subnet with the fewest live
+// instances, earlier subnets first, and the first minCount instances are the
+// ones required for the launch to succeed.
+func launchBatches(vm *v1.Vm, minCount, maxCount int64) []launchBatch {
+	if len(vm.Spec.SubnetIds) == 0 {
+		return []launchBatch{{subnetId: subnetId(vm), minCount: minCount, maxCount: maxCount}}
+	}
+
+	counts := make(map[string]int, len(vm.Spec.SubnetIds))
+	for _, instance := range liveInstances(vm) {
+		counts[instance.SubnetId]++
+	}
+	batches := make([]launchBatch, len(vm.Spec.SubnetIds))
+	for i, subnetId := range vm.Spec.SubnetIds {
+		batches[i].subnetId = subnetId
+	}
+	for n := int64(0); n < maxCount; n++ {
+		next := 0
+		for i, batch := range batches {
+			if counts[batch.subnetId] < counts[batches[next].subnetId] {
+				next = i
+			}
+		}
+		counts[batches[next].subnetId]++
+		batches[next].maxCount++
+		if n < minCount {
+			batches[next].minCount++
+		}
+	}
+
+	var planned []launchBatch
+	for _, batch := range batches {
+		if batch.maxCount == 0 {
+			continue
+		}
+		if batch.minCount == 0 {
+			// RunInstances always launches at least one instance
+			batch.minCount = 1
+		}
+		planned = append(planned, batch)
+	}
+	return planned
+}
+
+// batchToken derives the client token of one subnet of a spread launch from
+// the token of the whole launch, so every batch stays idempotent on retry.
+func batchToken(token, subnetId string) string {
+	sum := sha256.Sum256([]byte(token + "/" + subnetId))
+	return fmt.Sprintf("%x", sum)
+}
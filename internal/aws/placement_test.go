@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+func TestLaunchBatches(t *testing.T) {
+	running := ec2.InstanceStateNameRunning
+	tests := []struct {
+		name      string
+		subnetIds []string
+		subnetId  string
+		live      map[string]int
+		minCount  int64
+		maxCount  int64
+		want      []launchBatch
+	}{
+		{
+			name:     "single subnet",
+			subnetId: "subnet-a",
+			minCount: 1,
+			maxCount: 3,
+			want:     []launchBatch{{subnetId: "subnet-a", minCount: 1, maxCount: 3}},
+		},
+		{
+			name:      "round robin from the first subnet",
+			subnetIds: []string{"subnet-a", "subnet-b", "subnet-c"},
+			minCount:  1,
+			maxCount:  4,
+			want: []launchBatch{
+				{subnetId: "subnet-a", minCount: 1, maxCount: 2},
+				{subnetId: "subnet-b", minCount: 1, maxCount: 1},
+				{subnetId: "subnet-c", minCount: 1, maxCount: 1},
+			},
+		},
+		{
+			name:      "required instances follow the spread",
+			subnetIds: []string{"subnet-a", "subnet-b"},
+			minCount:  4,
+			maxCount:  4,
+			want: []launchBatch{
+				{subnetId: "subnet-a", minCount: 2, maxCount: 2},
+				{subnetId: "subnet-b", minCount: 2, maxCount: 2},
+			},
+		},
+		{
+			name:      "fills the subnets with the fewest live instances",
+			subnetIds: []string{"subnet-a", "subnet-b", "subnet-c"},
+			live:      map[string]int{"subnet-a": 2, "subnet-c": 1},
+			minCount:  2,
+			maxCount:  2,
+			want:      []launchBatch{{subnetId: "subnet-b", minCount: 2, maxCount: 2}},
+		},
+		{
+			name:      "subnets without instances are skipped",
+			subnetIds: []string{"subnet-a", "subnet-b", "subnet-c"},
+			live:      map[string]int{"subnet-a": 1, "subnet-b": 1},
+			minCount:  1,
+			maxCount:  1,
+			want:      []launchBatch{{subnetId: "subnet-c", minCount: 1, maxCount: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &v1.Vm{}
+			vm.Spec.SubnetIds = tt.subnetIds
+			vm.Spec.SubnetId = tt.subnetId
+			for _, subnetId := range tt.subnetIds {
+				for i := 0; i < tt.live[subnetId]; i++ {
+					vm.Status.InstanceStatus = append(vm.Status.InstanceStatus,
+						v1.InstanceStatus{InstanceId: "i-" + subnetId, State: running, SubnetId: subnetId})
+				}
+			}
+			if got := launchBatches(vm, tt.minCount, tt.maxCount); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("launchBatches() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
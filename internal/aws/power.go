@@ -0,0 +1,87 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// SyncPowerState starts or stops the instances recorded in the VM status so
+// that they converge on spec.powerState. Instances in a transitional state are
+// left alone until they settle.
+func (c *AwsSession) SyncPowerState(vm *v1.Vm) error {
+	if vm.Spec.DryRun {
+		return nil
+	}
+	svc := ec2.New(c.sess)
+
+	var toStart, toStop []*string
+	for _, instance := range vm.Status.InstanceStatus {
+		switch {
+		case updating(instance):
+			// In-place updates drive the power state themselves
+			continue
+		case spotInterrupted(instance) && instance.State == ec2.InstanceStateNameStopped:
+			// AWS resumes interrupted spot instances once capacity returns
+			continue
+		case reportOnly(vm, instance.InstanceId):
+			continue
+		case WantsRunning(vm) && instance.State == ec2.InstanceStateNameStopped:
+			toStart = append(toStart, aws.String(instance.InstanceId))
+		case !WantsRunning(vm) && instance.State == ec2.InstanceStateNameRunning:
+			toStop = append(toStop, aws.String(instance.InstanceId))
+		}
+	}
+
+	if len(toStart) > 0 {
+		output, err := svc.StartInstances(&ec2.StartInstancesInput{InstanceIds: toStart})
+		if err != nil {
+			return fmt.Errorf("failed to start instances %v: %w", aws.StringValueSlice(toStart), err)
+		}
+		recordStateChanges(vm, output.StartingInstances)
+	}
+	if len(toStop) > 0 {
+		output, err := svc.StopInstances(&ec2.StopInstancesInput{
+			InstanceIds: toStop,
+			Hibernate:   aws.Bool(vm.Spec.PowerState == v1.PowerStateHibernated),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to stop instances %v: %w", aws.StringValueSlice(toStop), err)
+		}
+		recordStateChanges(vm, output.StoppingInstances)
+	}
+	return nil
+}
+
+// WantsRunning reports whether the Vm instances should be running.
+func WantsRunning(vm *v1.Vm) bool {
+	return vm.Spec.PowerState == "" || vm.Spec.PowerState == v1.PowerStateRunning
+}
+
+// PowerStateReached reports whether every live instance is in the desired power state.
+func PowerStateReached(vm *v1.Vm) bool {
+	want := ec2.InstanceStateNameRunning
+	if !WantsRunning(vm) {
+		want = ec2.InstanceStateNameStopped
+	}
+	for _, instance := range liveInstances(vm) {
+		if instance.State != want {
+			return false
+		}
+	}
+	return true
+}
+
+// recordStateChanges copies the new instance states returned by a start,
+// stop or terminate call into the VM status.
+func recordStateChanges(vm *v1.Vm, changes []*ec2.InstanceStateChange) {
+	for _, change := range changes {
+		for i := range vm.Status.InstanceStatus {
+			if vm.Status.InstanceStatus[i].InstanceId == aws.StringValue(change.InstanceId) {
+				vm.Status.InstanceStatus[i].State = aws.StringValue(change.CurrentState.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// MaxUnavailable returns how many instances may be out of service at the same
+// time during an update.
+func MaxUnavailable(vm *v1.Vm) int {
+	if vm.Spec.UpdateStrategy == nil || vm.Spec.UpdateStrategy.MaxUnavailable == nil {
+		return 1
+	}
+	return *vm.Spec.UpdateStrategy.MaxUnavailable
+}
+
+// MaxSurge returns how many instances may be launched above the desired
+// count while instances are replaced.
+func MaxSurge(vm *v1.Vm) int {
+	if vm.Spec.UpdateStrategy == nil || vm.Spec.UpdateStrategy.MaxSurge == nil {
+		return 1
+	}
+	return *vm.Spec.UpdateStrategy.MaxSurge
+}
+
+// UpdateInProgress reports whether any instance is in the middle of an
+// in-place update.
+func UpdateInProgress(vm *v1.Vm) bool {
+	for _, instance := range vm.Status.InstanceStatus {
+		if updating(instance) {
+			return true
+		}
+	}
+	return false
+}
+
+// unavailableBudget returns how many more instances may be taken out of
+// service. Instances that are missing, not in the desired power state or being
+// updated in place already count against MaxUnavailable. At least one instance
+// may always be out of service, so in-place changes make progress with a
+// MaxUnavailable of 0.
+func unavailableBudget(vm *v1.Vm) int {
+	limit := MaxUnavailable(vm)
+	if limit < 1 {
+		limit = 1
+	}
+	available := 0
+	for _, instance := range liveInstances(vm) {
+		if serving(vm, instance) && !updating(instance) {
+			available++
+		}
+	}
+	unavailable := DesiredCount(vm) - available
+	if unavailable < 0 {
+		unavailable = 0
+	}
+	return limit - unavailable
+}
+
+func updating(instance v1.InstanceStatus) bool {
+	return instance.Update != nil && instance.Update.Phase != v1.InstanceUpdateFailed
+}
+
+// ResizeVM moves the instances to spec.instanceType in place. Running
+// instances are stopped, modified and started again within the budget left
+// by unavailableBudget, so health repairs and instances out of service for
+// any other reason count too; stopped instances are modified directly. Each
+// call advances the resize by one step and records the progress on the
+// instance status.
+func (c *AwsSession) ResizeVM(vm *v1.Vm) error {
+	target := vm.Spec.InstanceType
+	if target == "" || vm.Spec.DryRun {
+		return nil
+	}
+	svc := ec2.New(c.sess)
+
+	for i := range vm.Status.InstanceStatus {
+		instance := &vm.Status.InstanceStatus[i]
+		if instance.Update == nil || instance.Update.Type != v1.InstanceUpdateResize {
+			continue
+		}
+		if instance.Update.Phase == v1.InstanceUpdateFailed {
+			// Retry only once the spec asks for a different type
+			if instance.Update.Target != target {
+				instance.Update = nil
+			}
+			continue
+		}
+		if err := advanceResize(svc, vm, instance); err != nil {
+			return err
+		}
+	}
+
+	if RolloutInProgress(vm) {
+		// Replacement instances are launched with the new type anyway
+		return nil
+	}
+	budget := unavailableBudget(vm)
+	for i := range vm.Status.InstanceStatus {
+		instance := &vm.Status.InstanceStatus[i]
+		if instance.Update != nil || instance.InstanceType == "" || AcceptableInstanceType(vm, instance.InstanceType) {
+			continue
+		}
+		if reportOnly(vm, instance.InstanceId) {
+			continue
+		}
+		switch instance.State {
+		case ec2.InstanceStateNameStopped:
+			// Already out of service, so it does not use the budget
+			instance.Update = &v1.InstanceUpdate{Type: v1.InstanceUpdateResize, Target: target, Phase: v1.InstanceUpdateStopping}
+			if err := advanceResize(svc, vm, instance); err != nil {
+				return err
+			}
+		case ec2.InstanceStateNameRunning:
+			if budget <= 0 {
+				continue
+			}
+			_, err := svc.StopInstances(&ec2.StopInstancesInput{InstanceIds: []*string{aws.String(instance.InstanceId)}})
+			if err != nil {
+				return fmt.Errorf("failed to stop instance %s for resize: %w", instance.InstanceId, err)
+			}
+			instance.State = ec2.InstanceStateNameStopping
+			instance.Update = &v1.InstanceUpdate{Type: v1.InstanceUpdateResize, Target: target, Phase: v1.InstanceUpdateStopping}
+			budget--
+		}
+	}
+	return nil
+}
+
+// advanceResize moves a single instance resize to its next step once the
+// instance has settled in the state the current phase waits for.
+func advanceResize(svc *ec2.EC2, vm *v1.Vm, instance *v1.InstanceStatus) error {
+	update := instance.Update
+	switch {
+	case instance.State == ec2.InstanceStateNameStopped && update.Phase == v1.InstanceUpdateStopping:
+		_, err := svc.ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+			InstanceId:   aws.String(instance.InstanceId),
+			InstanceType: &ec2.AttributeValue{Value: aws.String(update.Target)},
+		})
+		if err != nil {
+			update.Phase = v1.InstanceUpdateFailed
+			update.Message = err.Error()
+		} else {
+			instance.InstanceType = update.Target
+		}
+		if !WantsRunning(vm) {
+			if update.Phase != v1.InstanceUpdateFailed {
+				instance.Update = nil
+			}
+			return nil
+		}
+		// Bring the instance back even when the modification failed
+		_, err = svc.StartInstances(&ec2.StartInstancesInput{InstanceIds: []*string{aws.String(instance.InstanceId)}})
+		if err != nil {
+			return fmt.Errorf("failed to start instance %s after resize: %w", instance.InstanceId, err)
+		}
+		instance.State = ec2.InstanceStateNamePending
+		if update.Phase != v1.InstanceUpdateFailed {
+			update.Phase = v1.InstanceUpdateStarting
+		}
+	case instance.State == ec2.InstanceStateNameRunning && update.Phase == v1.InstanceUpdateStarting:
+		instance.Update = nil
+	case instance.State == ec2.InstanceStateNameStopped && update.Phase == v1.InstanceUpdateStarting:
+		// The instance stopped again before it finished starting
+		if !WantsRunning(vm) {
+			instance.Update = nil
+			return nil
+		}
+		_, err := svc.StartInstances(&ec2.StartInstancesInput{InstanceIds: []*string{aws.String(instance.InstanceId)}})
+		if err != nil {
+			return fmt.Errorf("failed to start instance %s after resize: %w", instance.InstanceId, err)
+		}
+		instance.State = ec2.InstanceStateNamePending
+	case instance.State == ec2.InstanceStateNameRunning && update.Phase == v1.InstanceUpdateStopping:
+		// The instance was started again before the stop took effect
+		_, err := svc.StopInstances(&ec2.StopInstancesInput{InstanceIds: []*string{aws.String(instance.InstanceId)}})
+		if err != nil {
+			return fmt.Errorf("failed to stop instance %s for resize: %w", instance.InstanceId, err)
+		}
+		instance.State = ec2.InstanceStateNameStopping
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// ownershipTagKeys are the tags removed from retained resources.
+var ownershipTagKeys = []string{clusterTagKey, namespaceTagKey, vmNameTagKey, vmUIDTagKey, revisionTagKey}
+
+// StopVM stops the running instances of a Vm that is deleted with the Stop
+// policy and reports whether every instance has stopped.
+func (c *AwsSession) StopVM(vm *v1.Vm) (bool, error) {
+	svc := ec2.New(c.sess)
+
+	done := true
+	var ids []*string
+	for _, instance := range vm.Status.InstanceStatus {
+		switch instance.State {
+		case ec2.InstanceStateNameStopped, ec2.InstanceStateNameTerminated:
+		case ec2.InstanceStateNameRunning:
+			done = false
+			ids = append(ids, aws.String(instance.InstanceId))
+		default:
+			// Pending or stopping instances are stopped on a later call
+			done = false
+		}
+	}
+	if len(ids) == 0 {
+		return done, nil
+	}
+	output, err := svc.StopInstances(&ec2.StopInstancesInput{InstanceIds: ids})
+	if err != nil {
+		return false, fmt.Errorf("failed to stop instances %v: %w", aws.StringValueSlice(ids), err)
+	}
+	recordStateChanges(vm, output.StoppingInstances)
+	return false, nil
+}
+
+// RetainVM removes the ownership tags from the instances of a Vm deleted with
+// the Retain policy, along with their volumes, network interfaces and
+// Elastic IPs, so the resources outlive the Vm and can be adopted elsewhere.
+func (c *AwsSession) RetainVM(vm *v1.Vm) error {
+	svc := ec2.New(c.sess)
+
+	var resources []*string
+	if len(vm.Status.InstanceStatus) > 0 {
+		instances, err := describeInstances(svc, instanceIds(vm))
+		if err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			resources = append(resources, instanceResources(instance)...)
+		}
+	}
+	addresses, err := c.allocatedAddresses(svc, vm)
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		resources = append(resources, address.AllocationId)
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+
+	tags := make([]*ec2.Tag, len(ownershipTagKeys))
+	for i, key := range ownershipTagKeys {
+		tags[i] = &ec2.Tag{Key: aws.String(key)}
+	}
+	_, err = svc.DeleteTags(&ec2.DeleteTagsInput{Resources: resources, Tags: tags})
+	if err != nil {
+		return fmt.Errorf("failed to remove ownership tags from %v: %w", aws.StringValueSlice(resources), err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// SyncRevisions records the revision of the current spec and promotes it to
+// the current revision once no live instance of an older revision is left.
+func SyncRevisions(vm *v1.Vm) {
+	vm.Status.UpdateRevision = Revision(vm)
+	if vm.Status.CurrentRevision == "" {
+		vm.Status.CurrentRevision = vm.Status.UpdateRevision
+	}
+	if old, _ := splitByRevision(vm); len(old) == 0 {
+		vm.Status.CurrentRevision = vm.Status.UpdateRevision
+	}
+}
+
+// RolloutInProgress reports whether instances of an older revision are being
+// replaced. SyncRevisions must have been called first.
+func RolloutInProgress(vm *v1.Vm) bool {
+	return vm.Status.CurrentRevision != vm.Status.UpdateRevision
+}
+
+// NeedsLaunch reports whether the next scale or rollout step launches
+// instances, so the caller can persist a client token beforehand.
+func NeedsLaunch(vm *v1.Vm) bool {
+	if RolloutInProgress(vm) {
+		return rolloutLaunchCount(vm) > 0
+	}
+	return ScaleDelta(vm) > 0
+}
+
+// RollVM advances the replacement of instances launched from an older
+// revision: replacements are launched up to MaxSurge above the desired count,
+// and old instances are terminated once replacements are ready to take over
+// from them. Replacements are ready once they are running and pass their EC2
+// status checks.
+func (c *AwsSession) RollVM(vm *v1.Vm) error {
+	if vm.Spec.DryRun {
+		return nil
+	}
+	pruneTerminated(vm)
+
+	if count := rolloutLaunchCount(vm); count > 0 {
+		if err := c.launch(vm, 1, int64(count)); err != nil {
+			return err
+		}
+	}
+
+	svc := ec2.New(c.sess)
+	old, updated := splitByRevision(vm)
+	ready, err := readyInstances(svc, vm, updated)
+	if err != nil {
+		return err
+	}
+
+	victims := aws.StringSlice(rolloutVictims(vm, old, ready))
+	if len(victims) > 0 {
+		output, err := svc.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: victims})
+		if err != nil {
+			return fmt.Errorf("failed to terminate replaced instances %v: %w", aws.StringValueSlice(victims), err)
+		}
+		recordStateChanges(vm, output.TerminatingInstances)
+	}
+
+	SyncRevisions(vm)
+	return nil
+}
+
+// rolloutVictims picks the old instances to terminate given the number of
+// ready replacements. Old instances that are out of service anyway go first;
+// serving ones only make way for ready replacements, so the desired count
+// stays available. Without MaxSurge replacements are only launched once old
+// instances are gone, so up to MaxUnavailable of them go first.
+func rolloutVictims(vm *v1.Vm, old []v1.InstanceStatus, ready int) []string {
+	var victims []string
+	var availableOld []v1.InstanceStatus
+	for _, instance := range old {
+		if serving(vm, instance) {
+			availableOld = append(availableOld, instance)
+		} else {
+			victims = append(victims, instance.InstanceId)
+		}
+	}
+	removable := ready + len(availableOld) - DesiredCount(vm)
+	if MaxSurge(vm) == 0 {
+		removable += MaxUnavailable(vm)
+	}
+	for i := 0; i < removable && i < len(availableOld); i++ {
+		victims = append(victims, availableOld[i].InstanceId)
+	}
+	return victims
+}
+
+// rolloutLaunchCount returns how many replacement instances can be launched
+// without exceeding the desired count plus MaxSurge.
+func rolloutLaunchCount(vm *v1.Vm) int {
+	old, updated := splitByRevision(vm)
+	desired := DesiredCount(vm)
+	surge := MaxSurge(vm)
+	if surge == 0 && MaxUnavailable(vm) == 0 {
+		// Without either budget the rollout could never make progress
+		surge = 1
+	}
+
+	count := desired + surge - len(old) - len(updated)
+	if missing := desired - len(updated); count > missing {
+		count = missing
+	}
+	if count < 0 {
+		return 0
+	}
+	return count
+}
+
+// splitByRevision splits the live instances into those launched from an older
+// revision and those launched from the current spec. Instances without a
+// revision, such as adopted ones, are taken to run the current revision.
+func splitByRevision(vm *v1.Vm) (old, updated []v1.InstanceStatus) {
+	for _, instance := range liveInstances(vm) {
+		revision := instance.Revision
+		if revision == "" {
+			revision = vm.Status.CurrentRevision
+		}
+		if revision == vm.Status.UpdateRevision {
+			updated = append(updated, instance)
+		} else {
+			old = append(old, instance)
+		}
+	}
+	return old, updated
+}
+
+// serving reports whether the instance is in the power state the Vm asks for.
+func serving(vm *v1.Vm, instance v1.InstanceStatus) bool {
+	if WantsRunning(vm) {
+		return instance.State == ec2.InstanceStateNameRunning
+	}
+	return instance.State == ec2.InstanceStateNameStopped
+}
+
+// readyInstances counts the replacement instances that can take over from old
+// ones: running instances must also pass their status checks.
+func readyInstances(svc *ec2.EC2, vm *v1.Vm, instances []v1.InstanceStatus) (int, error) {
+	var running []string
+	ready := 0
+	for _, instance := range instances {
+		if !serving(vm, instance) {
+			continue
+		}
+		if instance.State == ec2.InstanceStateNameRunning {
+			running = append(running, instance.InstanceId)
+		} else {
+			ready++
+		}
+	}
+
+	passing, err := passingStatusChecks(svc, running)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range running {
+		if passing[id] {
+			ready++
+		}
+	}
+	return ready, nil
+}
+
+// pruneTerminated drops terminated instances from the VM status.
+func pruneTerminated(vm *v1.Vm) {
+	statuses := vm.Status.InstanceStatus[:0]
+	for _, instance := range vm.Status.InstanceStatus {
+		if instance.State != ec2.InstanceStateNameTerminated {
+			statuses = append(statuses, instance)
+		}
+	}
+	vm.Status.InstanceStatus = statuses
+}
@@ -0,0 +1,244 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// rolloutVm returns a Vm rolling out from revision "old" to revision "new".
+func rolloutVm(desired, maxSurge, maxUnavailable int, instances ...v1.InstanceStatus) *v1.Vm {
+	vm := &v1.Vm{}
+	vm.Spec.MinCount = desired
+	vm.Spec.MaxCount = desired
+	vm.Spec.UpdateStrategy = &v1.UpdateStrategy{MaxSurge: &maxSurge, MaxUnavailable: &maxUnavailable}
+	vm.Status.CurrentRevision = "old"
+	vm.Status.UpdateRevision = "new"
+	vm.Status.InstanceStatus = instances
+	return vm
+}
+
+func instance(id, state, revision string) v1.InstanceStatus {
+	return v1.InstanceStatus{InstanceId: id, State: state, Revision: revision}
+}
+
+func TestRolloutLaunchCount(t *testing.T) {
+	running := ec2.InstanceStateNameRunning
+	tests := []struct {
+		name string
+		vm   *v1.Vm
+		want int
+	}{
+		{
+			name: "surge of one",
+			vm:   rolloutVm(3, 1, 1, instance("i-1", running, "old"), instance("i-2", running, "old"), instance("i-3", running, "old")),
+			want: 1,
+		},
+		{
+			name: "surge of two",
+			vm:   rolloutVm(3, 2, 1, instance("i-1", running, "old"), instance("i-2", running, "old"), instance("i-3", running, "old")),
+			want: 2,
+		},
+		{
+			name: "surge used up",
+			vm: rolloutVm(3, 1, 1, instance("i-1", running, "old"), instance("i-2", running, "old"), instance("i-3", running, "old"),
+				instance("i-4", running, "new")),
+			want: 0,
+		},
+		{
+			name: "never more than the missing replacements",
+			vm:   rolloutVm(3, 3, 1, instance("i-1", running, "old"), instance("i-2", running, "new"), instance("i-3", running, "new")),
+			want: 1,
+		},
+		{
+			name: "no budget at all still surges by one",
+			vm:   rolloutVm(2, 0, 0, instance("i-1", running, "old"), instance("i-2", running, "old")),
+			want: 1,
+		},
+		{
+			name: "no surge replaces in place",
+			vm:   rolloutVm(2, 0, 1, instance("i-1", running, "old"), instance("i-2", running, "old")),
+			want: 0,
+		},
+		{
+			name: "terminated instances do not count",
+			vm: rolloutVm(2, 0, 1, instance("i-1", ec2.InstanceStateNameShuttingDown, "old"),
+				instance("i-2", running, "old")),
+			want: 1,
+		},
+		{
+			name: "rollout complete",
+			vm:   rolloutVm(2, 1, 1, instance("i-1", running, "new"), instance("i-2", running, "new")),
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rolloutLaunchCount(tt.vm); got != tt.want {
+				t.Errorf("rolloutLaunchCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitByRevision(t *testing.T) {
+	running := ec2.InstanceStateNameRunning
+	tests := []struct {
+		name        string
+		current     string
+		instances   []v1.InstanceStatus
+		wantOld     []string
+		wantUpdated []string
+	}{
+		{
+			name:    "by revision tag",
+			current: "old",
+			instances: []v1.InstanceStatus{
+				instance("i-1", running, "old"), instance("i-2", running, "new"), instance("i-3", running, "older"),
+			},
+			wantOld:     []string{"i-1", "i-3"},
+			wantUpdated: []string{"i-2"},
+		},
+		{
+			name:        "adopted instances run the current revision",
+			current:     "old",
+			instances:   []v1.InstanceStatus{instance("i-1", running, "")},
+			wantOld:     []string{"i-1"},
+			wantUpdated: nil,
+		},
+		{
+			name:        "adopted instances once the rollout completed",
+			current:     "new",
+			instances:   []v1.InstanceStatus{instance("i-1", running, "")},
+			wantOld:     nil,
+			wantUpdated: []string{"i-1"},
+		},
+		{
+			name:    "only live instances",
+			current: "old",
+			instances: []v1.InstanceStatus{
+				instance("i-1", ec2.InstanceStateNameTerminated, "old"), instance("i-2", ec2.InstanceStateNameStopped, "new"),
+			},
+			wantOld:     nil,
+			wantUpdated: []string{"i-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := rolloutVm(len(tt.instances), 1, 1, tt.instances...)
+			vm.Status.CurrentRevision = tt.current
+			old, updated := splitByRevision(vm)
+			if got := statusIds(old); !reflect.DeepEqual(got, tt.wantOld) {
+				t.Errorf("old = %v, want %v", got, tt.wantOld)
+			}
+			if got := statusIds(updated); !reflect.DeepEqual(got, tt.wantUpdated) {
+				t.Errorf("updated = %v, want %v", got, tt.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestRolloutVictims(t *testing.T) {
+	running, stopped := ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopped
+	old := []v1.InstanceStatus{instance("i-1", running, "old"), instance("i-2", running, "old"), instance("i-3", running, "old")}
+	tests := []struct {
+		name           string
+		desired        int
+		maxSurge       int
+		maxUnavailable int
+		old            []v1.InstanceStatus
+		ready          int
+		want           []string
+	}{
+		{
+			name:           "nothing before a replacement is ready",
+			desired:        3,
+			maxSurge:       1,
+			maxUnavailable: 1,
+			old:            old,
+			ready:          0,
+			want:           nil,
+		},
+		{
+			name:           "a ready replacement frees one old instance",
+			desired:        3,
+			maxSurge:       1,
+			maxUnavailable: 1,
+			old:            old,
+			ready:          1,
+			want:           []string{"i-1"},
+		},
+		{
+			name:           "single instance waits for its replacement",
+			desired:        1,
+			maxSurge:       1,
+			maxUnavailable: 1,
+			old:            []v1.InstanceStatus{instance("i-1", running, "old")},
+			ready:          0,
+			want:           nil,
+		},
+		{
+			name:           "single instance once its replacement is ready",
+			desired:        1,
+			maxSurge:       1,
+			maxUnavailable: 1,
+			old:            []v1.InstanceStatus{instance("i-1", running, "old")},
+			ready:          1,
+			want:           []string{"i-1"},
+		},
+		{
+			name:           "replacements already matched to old instances free nothing",
+			desired:        3,
+			maxSurge:       1,
+			maxUnavailable: 1,
+			old:            old[:2],
+			ready:          1,
+			want:           nil,
+		},
+		{
+			name:           "no surge takes maxUnavailable out first",
+			desired:        3,
+			maxSurge:       0,
+			maxUnavailable: 1,
+			old:            old,
+			ready:          0,
+			want:           []string{"i-1"},
+		},
+		{
+			name:           "out of service instances go first",
+			desired:        3,
+			maxSurge:       1,
+			maxUnavailable: 1,
+			old:            []v1.InstanceStatus{instance("i-1", running, "old"), instance("i-2", stopped, "old"), instance("i-3", running, "old")},
+			ready:          0,
+			want:           []string{"i-2"},
+		},
+		{
+			name:           "never more than the old instances",
+			desired:        3,
+			maxSurge:       3,
+			maxUnavailable: 3,
+			old:            old,
+			ready:          3,
+			want:           []string{"i-1", "i-2", "i-3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := rolloutVm(tt.desired, tt.maxSurge, tt.maxUnavailable)
+			if got := rolloutVictims(vm, tt.old, tt.ready); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rolloutVictims() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func statusIds(instances []v1.InstanceStatus) []string {
+	var ids []string
+	for _, instance := range instances {
+		ids = append(ids, instance.InstanceId)
+	}
+	return ids
+}
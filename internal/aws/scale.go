@@ -0,0 +1,143 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// DesiredCount returns the number of instances the Vm should run. RunInstances
+// is asked for MaxCount instances and accepts as few as MinCount, so MaxCount
+// is the target once the Vm exists.
+func DesiredCount(vm *v1.Vm) int {
+	if vm.Spec.MaxCount < vm.Spec.MinCount {
+		return vm.Spec.MinCount
+	}
+	return vm.Spec.MaxCount
+}
+
+// ScaleDelta returns how many instances have to be launched (positive) or
+// terminated (negative) to reach the desired count.
+func ScaleDelta(vm *v1.Vm) int {
+	return DesiredCount(vm) - len(liveInstances(vm))
+}
+
+// liveInstances returns the instances in the VM status that still count
+// towards the desired count.
+func liveInstances(vm *v1.Vm) []v1.InstanceStatus {
+	var live []v1.InstanceStatus
+	for _, instance := range vm.Status.InstanceStatus {
+		for _, state := range liveInstanceStates {
+			if instance.State == state {
+				live = append(live, instance)
+				break
+			}
+		}
+	}
+	return live
+}
+
+// ScaleVM launches or terminates instances until the number of live instances
+// matches the desired count. Terminated instances are dropped from the status.
+// A scale-up uses the client token persisted in the status, which the caller
+// must set before calling.
+func (c *AwsSession) ScaleVM(vm *v1.Vm) error {
+	if vm.Spec.DryRun {
+		return nil
+	}
+
+	pruneTerminated(vm)
+
+	live := liveInstances(vm)
+	delta := DesiredCount(vm) - len(live)
+	switch {
+	case delta > 0:
+		minCount := vm.Spec.MinCount - len(live)
+		if minCount < 1 {
+			minCount = 1
+		}
+		if minCount > delta {
+			minCount = delta
+		}
+		return c.launch(vm, int64(minCount), int64(delta))
+	case delta < 0:
+		return c.scaleDown(vm, live, -delta)
+	}
+	return nil
+}
+
+// scaleDown terminates count of the live instances, picked according to the
+// Vm scale-down policy.
+func (c *AwsSession) scaleDown(vm *v1.Vm, live []v1.InstanceStatus, count int) error {
+	svc := ec2.New(c.sess)
+
+	ids := make([]string, len(live))
+	for i, instance := range live {
+		ids[i] = instance.InstanceId
+	}
+	instances, err := describeInstances(svc, ids)
+	if err != nil {
+		return err
+	}
+
+	victims := scaleDownOrder(vm.Spec.ScaleDownPolicy, instances)
+	if count > len(victims) {
+		count = len(victims)
+	}
+	victimIds := make([]*string, count)
+	for i := range victimIds {
+		victimIds[i] = victims[i].InstanceId
+	}
+
+	output, err := svc.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: victimIds})
+	if err != nil {
+		return fmt.Errorf("failed to terminate instances %v: %w", aws.StringValueSlice(victimIds), err)
+	}
+	recordStateChanges(vm, output.TerminatingInstances)
+	return nil
+}
+
+// scaleDownOrder sorts the instances in the order they should be terminated.
+func scaleDownOrder(policy string, instances []*ec2.Instance) []*ec2.Instance {
+	ordered := append([]*ec2.Instance(nil), instances...)
+	newestFirst := func(i, j int) bool {
+		return aws.TimeValue(ordered[i].LaunchTime).After(aws.TimeValue(ordered[j].LaunchTime))
+	}
+
+	switch policy {
+	case v1.ScaleDownOldestFirst:
+		sort.SliceStable(ordered, func(i, j int) bool { return newestFirst(j, i) })
+	case v1.ScaleDownAvailabilityZone:
+		// Repeatedly take the newest instance from the zone with the most
+		// instances left, so the remaining fleet stays balanced across zones.
+		zones := map[string][]*ec2.Instance{}
+		sort.SliceStable(ordered, newestFirst)
+		for _, instance := range ordered {
+			zone := ""
+			if instance.Placement != nil {
+				zone = aws.StringValue(instance.Placement.AvailabilityZone)
+			}
+			zones[zone] = append(zones[zone], instance)
+		}
+		ordered = ordered[:0]
+		for len(zones) > 0 {
+			largest, found := "", false
+			for zone, members := range zones {
+				if !found || len(members) > len(zones[largest]) ||
+					(len(members) == len(zones[largest]) && zone < largest) {
+					largest, found = zone, true
+				}
+			}
+			ordered = append(ordered, zones[largest][0])
+			if zones[largest] = zones[largest][1:]; len(zones[largest]) == 0 {
+				delete(zones, largest)
+			}
+		}
+	default:
+		sort.SliceStable(ordered, newestFirst)
+	}
+	return ordered
+}
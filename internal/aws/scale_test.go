@@ -0,0 +1,78 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+func TestScaleDownOrder(t *testing.T) {
+	launched := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ec2Instance := func(id, zone string, age int) *ec2.Instance {
+		return &ec2.Instance{
+			InstanceId: aws.String(id),
+			LaunchTime: aws.Time(launched.Add(-time.Duration(age) * time.Hour)),
+			Placement:  &ec2.Placement{AvailabilityZone: aws.String(zone)},
+		}
+	}
+	// i-1 is the oldest and i-5 the newest instance
+	instances := []*ec2.Instance{
+		ec2Instance("i-3", "zone-a", 3),
+		ec2Instance("i-1", "zone-a", 5),
+		ec2Instance("i-5", "zone-b", 1),
+		ec2Instance("i-2", "zone-a", 4),
+		ec2Instance("i-4", "zone-b", 2),
+	}
+	tests := []struct {
+		name   string
+		policy string
+		want   []string
+	}{
+		{
+			name:   "newest first by default",
+			policy: "",
+			want:   []string{"i-5", "i-4", "i-3", "i-2", "i-1"},
+		},
+		{
+			name:   "newest first",
+			policy: v1.ScaleDownNewestFirst,
+			want:   []string{"i-5", "i-4", "i-3", "i-2", "i-1"},
+		},
+		{
+			name:   "oldest first",
+			policy: v1.ScaleDownOldestFirst,
+			want:   []string{"i-1", "i-2", "i-3", "i-4", "i-5"},
+		},
+		{
+			name:   "largest zone first, newest within a zone, ties by zone name",
+			policy: v1.ScaleDownAvailabilityZone,
+			want:   []string{"i-3", "i-2", "i-5", "i-1", "i-4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, instance := range scaleDownOrder(tt.policy, instances) {
+				got = append(got, aws.StringValue(instance.InstanceId))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scaleDownOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaleDownOrderKeepsInput(t *testing.T) {
+	instances := []*ec2.Instance{
+		{InstanceId: aws.String("i-1"), LaunchTime: aws.Time(time.Unix(1, 0))},
+		{InstanceId: aws.String("i-2"), LaunchTime: aws.Time(time.Unix(2, 0))},
+	}
+	scaleDownOrder(v1.ScaleDownNewestFirst, instances)
+	if aws.StringValue(instances[0].InstanceId) != "i-1" {
+		t.Errorf("scaleDownOrder() reordered its input")
+	}
+}
@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+// State reason codes AWS sets on spot instances it interrupted.
+const (
+	spotTerminationReason = "Server.SpotInstanceTermination"
+	spotShutdownReason    = "Server.SpotInstanceShutdown"
+)
+
+// spotCapacityErrors are the RunInstances error codes meaning spot capacity
+// is not available at the requested price.
+var spotCapacityErrors = map[string]bool{
+	"InsufficientInstanceCapacity":  true,
+	"InsufficientSpotCapacity":      true,
+	"SpotMaxPriceTooLow":            true,
+	"MaxSpotInstanceCountExceeded":  true,
+	"UnfulfillableCapacity":         true,
+	"InsufficientCapacityOnOutpost": true,
+}
+
+// instanceMarketOptions converts the Vm market options to their launch form.
+func instanceMarketOptions(opts *v1.InstanceMarketOptions) *ec2.InstanceMarketOptionsRequest {
+	marketType := opts.MarketType
+	if marketType == "" {
+		marketType = ec2.MarketTypeSpot
+	}
+	req := &ec2.InstanceMarketOptionsRequest{MarketType: aws.String(marketType)}
+	if spot := opts.SpotOptions; spot != nil {
+		req.SpotOptions = &ec2.SpotMarketOptions{}
+		if spot.MaxPrice != "" {
+			req.SpotOptions.MaxPrice = aws.String(spot.MaxPrice)
+		}
+		if spot.InstanceInterruptionBehavior != "" {
+			req.SpotOptions.InstanceInterruptionBehavior = aws.String(spot.InstanceInterruptionBehavior)
+		}
+		if spot.SpotInstanceType != "" {
+			req.SpotOptions.SpotInstanceType = aws.String(spot.SpotInstanceType)
+		}
+	}
+	return req
+}
+
+// fallbackToOnDemand reports whether a failed spot launch should be retried
+// on-demand.
+func fallbackToOnDemand(vm *v1.Vm, err error) bool {
+	if vm.Spec.InstanceMarketOptions == nil || !vm.Spec.InstanceMarketOptions.FallbackToOnDemand {
+		return false
+	}
+	return IsSpotCapacityError(err)
+}
+
+// SpotCapacityExhausted reports whether a spot Vm that does not fall back to
+// on-demand failed to launch for lack of spot capacity. The same errors on an
+// on-demand Vm are plain capacity errors.
+func SpotCapacityExhausted(vm *v1.Vm, err error) bool {
+	opts := vm.Spec.InstanceMarketOptions
+	return opts != nil && !opts.FallbackToOnDemand && IsSpotCapacityError(err)
+}
+
+// IsSpotCapacityError reports whether err means spot capacity is unavailable.
+func IsSpotCapacityError(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && spotCapacityErrors[aerr.Code()]
+}
+
+// onDemandInput copies a spot launch request without its market options. The
+// client token is derived from the spot one, as AWS rejects reusing a token
+// with different parameters.
+func onDemandInput(spotInput *ec2.RunInstancesInput) *ec2.RunInstancesInput {
+	input := *spotInput
+	input.InstanceMarketOptions = nil
+	if spotInput.ClientToken != nil {
+		input.ClientToken = aws.String(onDemandToken(aws.StringValue(spotInput.ClientToken)))
+	}
+	return &input
+}
+
+// onDemandToken derives the client token of the on-demand retry of a spot
+// launch from the spot one.
+func onDemandToken(token string) string {
+	sum := sha256.Sum256([]byte(token + "/on-demand"))
+	return fmt.Sprintf("%x", sum)
+}
+
+// InterruptedInstances returns the IDs of the spot instances AWS stopped or
+// terminated because of a spot interruption. Terminated ones are replaced by
+// the next scale step; stopped ones are resumed by AWS once capacity returns.
+func InterruptedInstances(vm *v1.Vm) []string {
+	var ids []string
+	for _, instance := range vm.Status.InstanceStatus {
+		if spotInterrupted(instance) {
+			ids = append(ids, instance.InstanceId)
+		}
+	}
+	return ids
+}
+
+func spotInterrupted(instance v1.InstanceStatus) bool {
+	return instance.StateReason == spotTerminationReason || instance.StateReason == spotShutdownReason
+}
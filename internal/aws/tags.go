@@ -0,0 +1,200 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+)
+
+const (
+	nameTagKey = "Name"
+
+	// Ownership tags identify the Vm that launched or adopted an instance.
+	clusterTagKey   = "aws.my.controller/cluster"
+	namespaceTagKey = "aws.my.controller/namespace"
+	vmNameTagKey    = "aws.my.controller/vm"
+	vmUIDTagKey     = "aws.my.controller/vm-uid"
+
+	// revisionTagKey records the spec revision an instance was launched from.
+	revisionTagKey = "aws.my.controller/revision"
+)
+
+// taggedResourceTypes are the resources created by RunInstances that carry the Vm tags.
+var taggedResourceTypes = []string{
+	ec2.ResourceTypeInstance,
+	ec2.ResourceTypeVolume,
+	ec2.ResourceTypeNetworkInterface,
+}
+
+// desiredTags returns the tags the Vm resources should carry: the spec tags
+// plus a Name tag from spec.name unless the spec tags already set one.
+func desiredTags(vm *v1.Vm) map[string]string {
+	tags := make(map[string]string, len(vm.Spec.Tags)+1)
+	for key, value := range vm.Spec.Tags {
+		tags[key] = value
+	}
+	if _, ok := tags[nameTagKey]; !ok && vm.Spec.Name != "" {
+		tags[nameTagKey] = vm.Spec.Name
+	}
+	return tags
+}
+
+// ownershipTags returns the tags marking the Vm resources as owned by this
+// controller, cluster and Vm object.
+func (c *AwsSession) ownershipTags(vm *v1.Vm) map[string]string {
+	return map[string]string{
+		clusterTagKey:   c.ClusterName,
+		namespaceTagKey: vm.Namespace,
+		vmNameTagKey:    vm.Name,
+		vmUIDTagKey:     string(vm.UID),
+	}
+}
+
+// launchTags returns the spec tags merged with the ownership tags, which take
+// precedence so they cannot be overridden from the spec.
+func (c *AwsSession) launchTags(vm *v1.Vm) map[string]string {
+	tags := desiredTags(vm)
+	for key, value := range c.ownershipTags(vm) {
+		tags[key] = value
+	}
+	return tags
+}
+
+// resourceTagSpecifications applies the tags to a single resource type.
+func resourceTagSpecifications(resourceType string, tags map[string]string) []*ec2.TagSpecification {
+	return []*ec2.TagSpecification{{ResourceType: aws.String(resourceType), Tags: ec2Tags(tags)}}
+}
+
+// tagSpecifications applies the tags to every resource type launched with the instances.
+func tagSpecifications(tags map[string]string) []*ec2.TagSpecification {
+	specs := make([]*ec2.TagSpecification, 0, len(taggedResourceTypes))
+	for _, resourceType := range taggedResourceTypes {
+		specs = append(specs, &ec2.TagSpecification{
+			ResourceType: aws.String(resourceType),
+			Tags:         ec2Tags(tags),
+		})
+	}
+	return specs
+}
+
+// SyncTags re-applies the spec and ownership tags to the instances, their
+// volumes and network interfaces, and removes tags that were dropped from the
+// spec. The instances are the ones described by GetExistingVM.
+func (c *AwsSession) SyncTags(vm *v1.Vm, instances []*ec2.Instance) error {
+	if len(instances) == 0 {
+		return nil
+	}
+	svc := ec2.New(c.sess)
+
+	keys := sortedKeys(desiredTags(vm))
+	desired := c.launchTags(vm)
+	var removed []string
+	for _, key := range vm.Status.ManagedTagKeys {
+		if _, ok := desired[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	specChanged := len(removed) > 0 || !equalStrings(keys, vm.Status.ManagedTagKeys)
+
+	for _, instance := range instances {
+		if state := recordedState(vm, aws.StringValue(instance.InstanceId)); state == "" || state == ec2.InstanceStateNameTerminated {
+			continue
+		}
+		if reportOnly(vm, aws.StringValue(instance.InstanceId)) {
+			continue
+		}
+		if !specChanged && tagsMatch(desired, instance.Tags) {
+			continue
+		}
+
+		resources := instanceResources(instance)
+		_, err := svc.CreateTags(&ec2.CreateTagsInput{Resources: resources, Tags: ec2Tags(desired)})
+		if err != nil {
+			return fmt.Errorf("failed to tag resources of %s: %w", aws.StringValue(instance.InstanceId), err)
+		}
+		if len(removed) > 0 {
+			deleteTags := make([]*ec2.Tag, 0, len(removed))
+			for _, key := range removed {
+				deleteTags = append(deleteTags, &ec2.Tag{Key: aws.String(key)})
+			}
+			_, err := svc.DeleteTags(&ec2.DeleteTagsInput{Resources: resources, Tags: deleteTags})
+			if err != nil {
+				return fmt.Errorf("failed to untag resources of %s: %w", aws.StringValue(instance.InstanceId), err)
+			}
+		}
+	}
+
+	vm.Status.ManagedTagKeys = keys
+	return nil
+}
+
+// instanceResources returns the instance ID followed by the IDs of its
+// attached EBS volumes and network interfaces.
+func instanceResources(instance *ec2.Instance) []*string {
+	resources := []*string{instance.InstanceId}
+	for _, device := range instance.BlockDeviceMappings {
+		if device.Ebs != nil && device.Ebs.VolumeId != nil {
+			resources = append(resources, device.Ebs.VolumeId)
+		}
+	}
+	for _, eni := range instance.NetworkInterfaces {
+		resources = append(resources, eni.NetworkInterfaceId)
+	}
+	return resources
+}
+
+// tagsMatch reports whether every desired tag is present with the same value.
+func tagsMatch(desired map[string]string, live []*ec2.Tag) bool {
+	liveTags := make(map[string]string, len(live))
+	for _, tag := range live {
+		liveTags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	for key, value := range desired {
+		if current, ok := liveTags[key]; !ok || current != value {
+			return false
+		}
+	}
+	return true
+}
+
+// tagValue returns the value of the tag with the given key, or "" when absent.
+func tagValue(tags []*ec2.Tag, key string) string {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value)
+		}
+	}
+	return ""
+}
+
+func ec2Tags(tags map[string]string) []*ec2.Tag {
+	out := make([]*ec2.Tag, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		out = append(out, &ec2.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
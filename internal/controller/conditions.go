@@ -0,0 +1,207 @@
+/*
+Copyright 2024 Srinivas.poturi.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+	"github.com/srinivas-poturi-3/aws-controller/internal/aws"
+)
+
+// Condition reasons
+const (
+	reasonCredentialsMissing    = "CredentialsMissing"
+	reasonLaunchFailed          = "LaunchFailed"
+	reasonSpotCapacity          = "SpotCapacityUnavailable"
+	reasonImageResolution       = "ImageResolutionFailed"
+	reasonNetworkResolution     = "NetworkResolutionFailed"
+	reasonDryRun                = "DryRun"
+	reasonProvisioned           = "Provisioned"
+	reasonSynced                = "Synced"
+	reasonDescribeFailed        = "DescribeFailed"
+	reasonScaleFailed           = "ScaleFailed"
+	reasonResizeFailed          = "ResizeFailed"
+	reasonPowerStateFailed      = "PowerStateFailed"
+	reasonElasticIpFailed       = "ElasticIpFailed"
+	reasonMetadataOptionsFailed = "MetadataOptionsFailed"
+	reasonTagsFailed            = "TagsFailed"
+	reasonSecurityGroupsFailed  = "SecurityGroupsFailed"
+	reasonSourceDestCheckFailed = "SourceDestCheckFailed"
+	reasonDrifted               = "Drifted"
+	reasonHealthCheckFailed     = "HealthCheckFailed"
+	reasonRemediated            = "Remediated"
+	reasonStatusCheckFailed     = "StatusCheckFailed"
+	reasonEventsScheduled       = "EventsScheduled"
+	reasonChecksInitializing    = "ChecksInitializing"
+	reasonHealthy               = "Healthy"
+	reasonDeleteFailed          = "DeleteFailed"
+	reasonDeleting              = "Deleting"
+	reasonAsExpected            = "AsExpected"
+	reasonSpotInterrupted       = "SpotInterrupted"
+	reasonUpdateFailed          = "UpdateFailed"
+	reasonInstancesMissing      = "InstancesMissing"
+	reasonPending               = "Pending"
+	reasonScaling               = "Scaling"
+	reasonRollingOut            = "RollingOut"
+	reasonUpdating              = "Updating"
+	reasonReady                 = "Ready"
+)
+
+// setCondition records a condition observed for the current generation.
+func setCondition(vm *v1.Vm, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&vm.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: vm.Generation,
+	})
+}
+
+// syncFailed records the failed reconcile step in the Synced condition and
+// persists the status, so whatever the step changed before failing is kept.
+// Errors AWS may recover from are retried with an exponential backoff; an
+// invalid spec sets the Failed condition and waits for the spec to change.
+func (r *VmReconciler) syncFailed(ctx context.Context, vm *v1.Vm, reason string, err error) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	class := aws.Classify(err)
+	setCondition(vm, v1.ConditionSynced, metav1.ConditionFalse, reason, err.Error())
+	vm.Status.ObservedGeneration = vm.Generation
+	result := ctrl.Result{}
+	if class.Retryable() {
+		result.RequeueAfter = retryInterval(class, vm.Status.Retries)
+		vm.Status.Retries++
+	} else {
+		vm.Status.Status = string(failed)
+		setCondition(vm, v1.ConditionFailed, metav1.ConditionTrue, conditionReason(aws.ErrorCode(err)), err.Error())
+		setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reason, err.Error())
+	}
+	if updateErr := r.Status().Update(ctx, vm); updateErr != nil {
+		log.Error(updateErr, "failed to update CRD status")
+		return ctrl.Result{}, updateErr
+	}
+	log.Info("Reconcile failed", "class", class, "code", aws.ErrorCode(err), "retryAfter", result.RequeueAfter)
+	return result, nil
+}
+
+// launchFailed records that the instances could not be provisioned.
+func launchFailed(vm *v1.Vm, reason string, err error) {
+	setCondition(vm, v1.ConditionProvisioned, metav1.ConditionFalse, reason, err.Error())
+	setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reason, err.Error())
+}
+
+// updateConditions derives the Provisioned, Synced, Degraded, Healthy and
+// Ready conditions from a successfully reconciled Vm.
+func updateConditions(vm *v1.Vm) {
+	vm.Status.ObservedGeneration = vm.Generation
+	vm.Status.Retries = 0
+	setCondition(vm, v1.ConditionSynced, metav1.ConditionTrue, reasonSynced, "")
+	setCondition(vm, v1.ConditionFailed, metav1.ConditionFalse, reasonAsExpected, "")
+
+	if vm.Spec.DryRun {
+		message := ""
+		if vm.Status.DryRun != nil {
+			message = vm.Status.DryRun.Code + ": " + vm.Status.DryRun.Message
+		}
+		setCondition(vm, v1.ConditionProvisioned, metav1.ConditionFalse, reasonDryRun, message)
+		setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reasonDryRun, "dry run only")
+		return
+	}
+	setCondition(vm, v1.ConditionProvisioned, metav1.ConditionTrue, reasonProvisioned,
+		fmt.Sprintf("%d instances", len(vm.Status.InstanceStatus)))
+
+	updateHealthCondition(vm)
+
+	var degraded []string
+	degradedReason := reasonAsExpected
+	if interrupted := aws.InterruptedInstances(vm); len(interrupted) != 0 {
+		degradedReason = reasonSpotInterrupted
+		degraded = append(degraded, fmt.Sprintf("spot instances interrupted: %s", strings.Join(interrupted, ", ")))
+	}
+	for _, instance := range vm.Status.InstanceStatus {
+		if instance.Update != nil && instance.Update.Phase == v1.InstanceUpdateFailed {
+			if degradedReason == reasonAsExpected {
+				degradedReason = reasonUpdateFailed
+			}
+			degraded = append(degraded, fmt.Sprintf("%s of %s failed: %s", instance.Update.Type, instance.InstanceId, instance.Update.Message))
+		}
+	}
+	if vm.Spec.DriftPolicy == v1.DriftPolicyReport {
+		for _, instance := range vm.Status.InstanceStatus {
+			if len(instance.Drift) == 0 {
+				continue
+			}
+			if degradedReason == reasonAsExpected {
+				degradedReason = reasonDrifted
+			}
+			degraded = append(degraded, fmt.Sprintf("%s drifted: %s", instance.InstanceId, strings.Join(instance.Drift, ", ")))
+		}
+	}
+	if delta := aws.ScaleDelta(vm); delta > 0 {
+		if degradedReason == reasonAsExpected {
+			degradedReason = reasonInstancesMissing
+		}
+		degraded = append(degraded, fmt.Sprintf("%d of %d instances missing", delta, aws.DesiredCount(vm)))
+	}
+	if len(degraded) != 0 {
+		setCondition(vm, v1.ConditionDegraded, metav1.ConditionTrue, degradedReason, strings.Join(degraded, "; "))
+	} else {
+		setCondition(vm, v1.ConditionDegraded, metav1.ConditionFalse, reasonAsExpected, "")
+	}
+
+	switch {
+	case aws.RolloutInProgress(vm):
+		setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reasonRollingOut, "replacing instances of revision "+vm.Status.CurrentRevision)
+	case aws.UpdateInProgress(vm):
+		setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reasonUpdating, "instances are being updated in place")
+	case aws.ScaleDelta(vm) != 0:
+		setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reasonScaling,
+			fmt.Sprintf("%d of %d instances", aws.DesiredCount(vm)-aws.ScaleDelta(vm), aws.DesiredCount(vm)))
+	case powerStatus(vm) == pending:
+		setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reasonPending, "instances are changing power state")
+	default:
+		setCondition(vm, v1.ConditionReady, metav1.ConditionTrue, reasonReady, "")
+	}
+}
+
+// updateHealthCondition sets Healthy from the status checks of the running
+// instances: False when a check is impaired, Unknown while the checks have no
+// result yet. Pending scheduled events are listed in the message.
+func updateHealthCondition(vm *v1.Vm) {
+	health := aws.CheckHealth(vm)
+	switch {
+	case len(health.Impaired) != 0:
+		setCondition(vm, v1.ConditionHealthy, metav1.ConditionFalse, reasonStatusCheckFailed,
+			strings.Join(append(health.Impaired, health.Events...), "; "))
+	case len(health.Initializing) != 0:
+		setCondition(vm, v1.ConditionHealthy, metav1.ConditionUnknown, reasonChecksInitializing,
+			"status checks pending for "+strings.Join(health.Initializing, ", "))
+	case len(health.Events) != 0:
+		setCondition(vm, v1.ConditionHealthy, metav1.ConditionTrue, reasonEventsScheduled, strings.Join(health.Events, "; "))
+	default:
+		setCondition(vm, v1.ConditionHealthy, metav1.ConditionTrue, reasonHealthy, "")
+	}
+}
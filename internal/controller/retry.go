@@ -0,0 +1,95 @@
+/*
+Copyright 2024 Srinivas.poturi.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"math/rand"
+	"regexp"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
+	"github.com/srinivas-poturi-3/aws-controller/internal/aws"
+)
+
+const (
+	// maxRetryInterval caps the backoff between retries of a failing Vm.
+	maxRetryInterval = 10 * time.Minute
+)
+
+// invalidReasonChars are the characters AWS error codes may contain that a
+// condition reason may not.
+var invalidReasonChars = regexp.MustCompile(`[^A-Za-z0-9_,:]`)
+
+// retryInterval returns the exponential backoff with jitter before the next
+// attempt after retries failures of the given class. Throttling, quota and
+// permission errors start from a longer interval, as retrying them quickly
+// does not help.
+func retryInterval(class aws.ErrorClass, retries int) time.Duration {
+	base := 5 * time.Second
+	switch class {
+	case aws.ErrorThrottling:
+		base = 15 * time.Second
+	case aws.ErrorQuota, aws.ErrorAuth:
+		base = time.Minute
+	}
+	interval := maxRetryInterval
+	if retries < 16 {
+		if backoff := base << uint(retries); backoff < maxRetryInterval {
+			interval = backoff
+		}
+	}
+	// Equal jitter: keep half of the interval and randomise the rest
+	return interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+}
+
+// conditionReason turns an AWS error code into a valid condition reason.
+func conditionReason(code string) string {
+	return invalidReasonChars.ReplaceAllString(code, "_")
+}
+
+// failedForGeneration reports whether AWS rejected the current spec as
+// invalid, in which case the Vm is left alone until the spec changes.
+func failedForGeneration(vm *v1.Vm) bool {
+	failed := meta.FindStatusCondition(vm.Status.Conditions, v1.ConditionFailed)
+	return failed != nil && failed.Status == metav1.ConditionTrue && failed.ObservedGeneration == vm.Generation
+}
+
+// resumeFailed moves a failed Vm back to the status it is retried from,
+// unless AWS rejected its current spec as invalid. It reports whether the Vm
+// is reconciled further.
+func resumeFailed(vm *v1.Vm) bool {
+	if vm.Status.Status != string(failed) {
+		return true
+	}
+	if failedForGeneration(vm) {
+		return false
+	}
+	if meta.IsStatusConditionTrue(vm.Status.Conditions, v1.ConditionFailed) {
+		// The rejected launch started nothing, and the changed spec
+		// needs a client token of its own
+		vm.Status.ClientToken = ""
+	}
+	if len(vm.Status.InstanceStatus) == 0 {
+		vm.Status.Status = string(initialized)
+	} else {
+		vm.Status.Status = string(pending)
+	}
+	return true
+}
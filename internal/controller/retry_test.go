@@ -0,0 +1,78 @@
+/*
+Copyright 2024 Srinivas.poturi.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/srinivas-poturi-3/aws-controller/internal/aws"
+)
+
+func TestRetryInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		class   aws.ErrorClass
+		retries int
+		want    time.Duration
+	}{
+		{name: "first retry", class: aws.ErrorUnknown, retries: 0, want: 5 * time.Second},
+		{name: "doubles per retry", class: aws.ErrorTransient, retries: 3, want: 40 * time.Second},
+		{name: "throttling starts slower", class: aws.ErrorThrottling, retries: 0, want: 15 * time.Second},
+		{name: "quota starts at a minute", class: aws.ErrorQuota, retries: 0, want: time.Minute},
+		{name: "auth starts at a minute", class: aws.ErrorAuth, retries: 2, want: 4 * time.Minute},
+		{name: "capped", class: aws.ErrorTransient, retries: 10, want: maxRetryInterval},
+		{name: "no overflow", class: aws.ErrorThrottling, retries: 100, want: maxRetryInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Equal jitter keeps the interval between half and all of the backoff
+			for i := 0; i < 100; i++ {
+				got := retryInterval(tt.class, tt.retries)
+				if got < tt.want/2 || got > tt.want {
+					t.Fatalf("retryInterval() = %s, want between %s and %s", got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionReason(t *testing.T) {
+	// The pattern the API server validates condition reasons against
+	valid := regexp.MustCompile(`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`)
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "InvalidParameterValue", want: "InvalidParameterValue"},
+		{code: "InvalidAMIID.NotFound", want: "InvalidAMIID_NotFound"},
+		{code: "InvalidParameterCombination", want: "InvalidParameterCombination"},
+		{code: "Invalid-Code With Spaces", want: "Invalid_Code_With_Spaces"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got := conditionReason(tt.code)
+			if got != tt.want {
+				t.Errorf("conditionReason() = %q, want %q", got, tt.want)
+			}
+			if !valid.MatchString(got) {
+				t.Errorf("conditionReason() = %q is not a valid condition reason", got)
+			}
+		})
+	}
+}
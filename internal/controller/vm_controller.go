@@ -18,14 +18,22 @@ package controller
 
 import (
 	"context"
-
+	"fmt"
+	"reflect"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	v1 "github.com/srinivas-poturi-3/aws-controller/api/v1"
 	"github.com/srinivas-poturi-3/aws-controller/internal/aws"
@@ -35,6 +43,12 @@ import (
 type VmReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// ClusterName is recorded in the ownership tags of every instance.
+	ClusterName string
+
+	// Recorder emits the events recorded on Vm objects, such as drift.
+	Recorder record.EventRecorder
 }
 
 type Status string
@@ -47,14 +61,20 @@ const (
 	pending     Status = "Pending"
 	failed      Status = "Failed"
 	terminated  Status = "Terminated"
+	dryRun      Status = "DryRun"
 )
 const (
 	controllerFinalizer string = "aws.my.controller/finalizer"
+
+	// updateRequeueInterval is how often a Vm is reconciled while its
+	// instances are being updated in place.
+	updateRequeueInterval = 15 * time.Second
 )
 
 //+kubebuilder:rbac:groups=aws.my.controller,resources=vms,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=aws.my.controller,resources=vms/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=aws.my.controller,resources=vms/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -71,35 +91,29 @@ func (r *VmReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Re
 	var vm v1.Vm
 	err := r.Get(ctx, req.NamespacedName, &vm)
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Deleted once the finalizer was released
+			return ctrl.Result{}, nil
+		}
 		log.Error(err, "unable to get CRD object")
 		return ctrl.Result{}, err
 	}
 
-	// Extract secret reference
-	var secretRef *v1.CredentialsSecret
-	if vm.CredentialsSecretRef.Name != "" {
-		secretRef = &vm.CredentialsSecretRef
-		secretRef.Namespace = vm.Namespace
+	// Handle VM deletion from any status
+	if vm.GetDeletionTimestamp() != nil {
+		return r.reconcileDelete(ctx, &vm)
 	}
 
 	// Check if credentials secret is specified
+	secretRef := credentialsSecretRef(&vm)
 	if secretRef == nil {
 		log.Info("Credentials secret not specified in CRD. Skipping AWS actions.")
-		vm.Status.Status = string(failed)
-		vm.Status.Error = "Credentials secret not specified in CRD"
-		r.Status().Update(ctx, &vm)
+		r.credentialsMissing(ctx, &vm)
 		return ctrl.Result{}, nil
 	}
 
-	// Retrieve AWS credentials from secret
-	secret, err := aws.GetAWSCredentials(ctx, r.Client, secretRef)
-	if err != nil {
-		log.Error(err, "failed to retrieve AWS credentials")
-		return ctrl.Result{}, err
-	}
-
-	// Create AWS session
-	awsSession, err := aws.GetSession(ctx, secret)
+	// Create AWS session from the credentials secret
+	awsSession, err := r.awsSession(ctx, secretRef)
 	if err != nil {
 		log.Error(err, "unable to create AWS session")
 		return ctrl.Result{}, err
@@ -116,71 +130,441 @@ func (r *VmReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Re
 			return ctrl.Result{}, err
 		}
 	}
+	if !resumeFailed(&vm) {
+		return ctrl.Result{}, nil
+	}
 	// Handle VM management
 	switch {
-	// Handle VM deletion
-	case vm.GetDeletionTimestamp() != nil && vm.Status.Status == string(running):
-		if controllerutil.ContainsFinalizer(&vm, controllerFinalizer) {
-			err := awsSession.DeleteVM(&vm)
-			if err != nil {
-				log.Error(err, "failed to delete VM")
-				return ctrl.Result{}, err
-			}
-			// Update CRD status to reflect deletion
-			vm.Status.Status = string(delete)
-			err = r.Status().Update(ctx, &vm)
+	// Handle VM creation, including the first real launch after a dry run, a
+	// new dry run of a changed spec and a running Vm whose instance status was
+	// lost. An Initialized Vm is a launch that was interrupted and is retried
+	// with its persisted client token.
+	case vm.Status.Status == "" || vm.Status.Status == string(initialized) ||
+		(vm.Status.Status == string(dryRun) && (!vm.Spec.DryRun || vm.Generation != vm.Status.ObservedGeneration)) ||
+		(provisioned(&vm) && len(vm.Status.InstanceStatus) == 0):
+		return r.reconcileLaunch(ctx, &vm, awsSession)
 
-			if ok := controllerutil.RemoveFinalizer(&vm, controllerFinalizer); !ok {
-				log.Error(err, "Failed to remove finalizer for controller")
-				return ctrl.Result{Requeue: true}, nil
-			}
-			return ctrl.Result{}, err
-		}
-	// Handle VM creation
-	case vm.Status.Status == "":
+	// Handle VM status updates
+	case (provisioned(&vm) || vm.Status.Status == string(delete)) && len(vm.Status.InstanceStatus) != 0:
+		return r.reconcileExisting(ctx, &vm, awsSession)
+	}
 
-		vm.Status.Status = string(initialized)
-		err = r.Status().Update(ctx, &vm)
-		if err != nil {
+	return ctrl.Result{}, nil
+}
+
+// reconcileDelete applies the deletion policy of a Vm marked for deletion and
+// releases its finalizer once the policy has been applied. Instances are
+// looked up by their ownership tags too, so an interrupted launch does not
+// leak them.
+func (r *VmReconciler) reconcileDelete(ctx context.Context, vm *v1.Vm) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(vm, controllerFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	// Leave the instances behind when asked to, as without credentials they
+	// cannot be cleaned up
+	if vm.Annotations[v1.OrphanInstancesAnnotation] == "true" {
+		log.Info("Releasing the Vm without cleaning up its instances", "instances", len(vm.Status.InstanceStatus))
+		return ctrl.Result{}, r.removeFinalizer(ctx, vm)
+	}
+
+	secretRef := credentialsSecretRef(vm)
+	if secretRef == nil {
+		log.Info("Credentials secret not specified in CRD. Skipping AWS actions.")
+		if len(vm.Status.InstanceStatus) == 0 {
+			// Nothing was launched, so there is nothing to clean up
+			return ctrl.Result{}, r.removeFinalizer(ctx, vm)
+		}
+		r.credentialsMissing(ctx, vm)
+		return ctrl.Result{}, nil
+	}
+	awsSession, err := r.awsSession(ctx, secretRef)
+	if apierrors.IsNotFound(err) {
+		// Usually the namespace is being deleted together with the Secret.
+		// Wait for the Secret to be restored instead of failing in a loop.
+		log.Info("Credentials secret of the deleting VM not found", "secret", secretRef.Name)
+		setCondition(vm, v1.ConditionDeleting, metav1.ConditionFalse, reasonCredentialsMissing,
+			fmt.Sprintf("%v: restore the Secret to apply the deletion policy, or annotate the Vm with %s=true to leave the instances in AWS",
+				err, v1.OrphanInstancesAnnotation))
+		if err = r.Status().Update(ctx, vm); err != nil {
 			log.Error(err, "failed to update CRD status")
 			return ctrl.Result{}, err
 		}
-		// Create VM
-		err = awsSession.CreateVM(&vm)
-		if err != nil {
-			vm.Status.Status = string(failed)
-			r.Status().Update(ctx, &vm)
-			log.Error(err, "failed to create VM")
-			return ctrl.Result{}, err
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+	if err != nil {
+		log.Error(err, "unable to create AWS session")
+		return ctrl.Result{}, err
+	}
+
+	vm.Status.Status = string(delete)
+	setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reasonDeleting, "the Vm is being deleted")
+	err = awsSession.FindOwnedInstances(vm)
+	if err != nil {
+		log.Error(err, "failed to look up the instances of the VM")
+		return r.syncFailed(ctx, vm, reasonDeleteFailed, err)
+	}
+	done := true
+	switch vm.Spec.DeletionPolicy {
+	case v1.DeletionPolicyRetain:
+		setCondition(vm, v1.ConditionDeleting, metav1.ConditionTrue, reasonDeleting, "releasing instances")
+		err = awsSession.RetainVM(vm)
+	case v1.DeletionPolicyStop:
+		setCondition(vm, v1.ConditionDeleting, metav1.ConditionTrue, reasonDeleting, "stopping instances")
+		done, err = awsSession.StopVM(vm)
+	default:
+		setCondition(vm, v1.ConditionDeleting, metav1.ConditionTrue, reasonDeleting, "terminating instances")
+		done, err = awsSession.DeleteVM(vm)
+		// Addresses can only be released once their instances are gone
+		if err == nil && done {
+			err = awsSession.ReleaseElasticIps(vm)
+		}
+	}
+	if err != nil {
+		log.Error(err, "failed to delete VM")
+		return r.syncFailed(ctx, vm, reasonDeleteFailed, err)
+	}
+	// Update CRD status to reflect deletion
+	err = r.Status().Update(ctx, vm)
+	if err != nil {
+		log.Error(err, "failed to update CRD status")
+		return ctrl.Result{}, err
+	}
+	if !done {
+		// Poll until the instances reached their final state
+		return ctrl.Result{RequeueAfter: updateRequeueInterval}, nil
+	}
+	return ctrl.Result{}, r.removeFinalizer(ctx, vm)
+}
+
+// reconcileLaunch launches the instances of a Vm, or dry-runs the launch,
+// after adopting the requested or already owned instances so a lost status
+// never leads to duplicates.
+func (r *VmReconciler) reconcileLaunch(ctx context.Context, vm *v1.Vm, awsSession *aws.AwsSession) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	vm.Status.Status = string(initialized)
+	if vm.Status.ClientToken == "" && !vm.Spec.DryRun && aws.DesiredCount(vm) > 0 {
+		vm.Status.ClientToken = aws.LaunchToken(vm)
+	}
+	// Persist the launch intent before calling AWS
+	err := r.Status().Update(ctx, vm)
+	if err != nil {
+		log.Error(err, "failed to update CRD status")
+		return ctrl.Result{}, err
+	}
+	err = awsSession.ResolveImage(vm)
+	if err != nil {
+		vm.Status.Status = string(failed)
+		launchFailed(vm, reasonImageResolution, err)
+		log.Error(err, "failed to resolve image")
+		return r.syncFailed(ctx, vm, reasonImageResolution, err)
+	}
+	err = awsSession.ResolveNetwork(vm)
+	if err != nil {
+		vm.Status.Status = string(failed)
+		launchFailed(vm, reasonNetworkResolution, err)
+		log.Error(err, "failed to resolve subnet and security groups")
+		return r.syncFailed(ctx, vm, reasonNetworkResolution, err)
+	}
+	if len(vm.Spec.AdoptInstanceIds) > 0 {
+		err = awsSession.AdoptVM(vm)
+	} else {
+		err = awsSession.DiscoverVM(vm)
+		if err == nil && len(vm.Status.InstanceStatus) != 0 {
+			log.Info("Adopting existing instances owned by the Vm", "instances", len(vm.Status.InstanceStatus))
+		}
+		// Create VM, unless it is scaled to zero
+		if err == nil && len(vm.Status.InstanceStatus) == 0 && aws.DesiredCount(vm) > 0 {
+			err = awsSession.CreateVM(vm)
+		}
+	}
+	if err != nil {
+		vm.Status.Status = string(failed)
+		reason := reasonLaunchFailed
+		if aws.SpotCapacityExhausted(vm, err) {
+			reason = reasonSpotCapacity
+			err = fmt.Errorf("spot capacity unavailable and fallbackToOnDemand is disabled: %w", err)
 		}
-		vm.Status.Status = string(running)
-		err = r.Status().Update(ctx, &vm)
+		launchFailed(vm, reason, err)
+		log.Error(err, "failed to create VM")
+		return r.syncFailed(ctx, vm, reason, err)
+	}
+	updateConditions(vm)
+	if vm.Spec.DryRun {
+		vm.Status.Status = string(dryRun)
+		err = r.Status().Update(ctx, vm)
 		if err != nil {
 			log.Error(err, "failed to update CRD status")
-			return ctrl.Result{}, err
 		}
-		return ctrl.Result{RequeueAfter: time.Minute}, nil
+		return ctrl.Result{}, err
+	}
+	vm.Status.Status = string(powerStatus(vm))
+	err = r.Status().Update(ctx, vm)
+	if err != nil {
+		log.Error(err, "failed to update CRD status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: time.Minute}, nil
+}
 
-	// Handle VM status updates
-	case (vm.Status.Status == string(running) || vm.Status.Status == string(delete)) && len(vm.Status.InstanceStatus) != 0:
-		err := awsSession.GetExistingVM(&vm)
+// reconcileExisting refreshes the status of a provisioned Vm from AWS and
+// moves its instances towards the spec: drift is detected, instances are
+// scaled, rolled out and updated in place, and their attributes are synced.
+func (r *VmReconciler) reconcileExisting(ctx context.Context, vm *v1.Vm, awsSession *aws.AwsSession) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	previous := append([]v1.InstanceStatus(nil), vm.Status.InstanceStatus...)
+	instances, err := awsSession.GetExistingVM(vm)
+	if err != nil {
+		log.Error(err, "failed to check existing VM")
+		return r.syncFailed(ctx, vm, reasonDescribeFailed, err)
+	}
+	// Interrupted spot instances that were terminated are replaced by the
+	// scale step below
+	if interrupted := aws.InterruptedInstances(vm); len(interrupted) != 0 {
+		log.Info("Spot instances were interrupted", "instances", interrupted)
+	}
+	for _, drift := range awsSession.DetectDrift(vm, previous, instances) {
+		log.Info("Instance drifted", "instance", drift.InstanceId, "field", drift.Field)
+		r.Recorder.Eventf(vm, corev1.EventTypeWarning, reasonDrifted, "%s", driftMessage(drift))
+	}
+	if vm.Spec.DriftPolicy == v1.DriftPolicyReplace && vm.Status.Status != string(delete) {
+		// Replacements are launched by the scale step below
+		err = awsSession.ReplaceDrifted(vm)
 		if err != nil {
-			log.Error(err, "failed to check existing VM")
-			return ctrl.Result{}, err
+			log.Error(err, "failed to replace drifted instances")
+			return r.syncFailed(ctx, vm, reasonScaleFailed, err)
 		}
-		err = r.Status().Update(ctx, &vm)
+	}
+	// A changed image selector, or a Rollout one resolving to a new AMI,
+	// changes the revision and replaces the instances
+	err = awsSession.ResolveImage(vm)
+	if err != nil {
+		log.Error(err, "failed to resolve image")
+		return r.syncFailed(ctx, vm, reasonImageResolution, err)
+	}
+	// Replace instances of an older revision, or launch and terminate
+	// instances to match the desired count
+	aws.SyncRevisions(vm)
+	if aws.NeedsLaunch(vm) && vm.Status.ClientToken == "" && !vm.Spec.DryRun {
+		// Selectors are resolved again for every new launch
+		err = awsSession.ResolveNetwork(vm)
 		if err != nil {
+			log.Error(err, "failed to resolve subnet and security groups")
+			return r.syncFailed(ctx, vm, reasonNetworkResolution, err)
+		}
+		vm.Status.ClientToken = aws.LaunchToken(vm)
+		// Persist the launch intent before calling AWS
+		if err = r.Status().Update(ctx, vm); err != nil {
 			log.Error(err, "failed to update CRD status")
 			return ctrl.Result{}, err
 		}
 	}
+	if aws.RolloutInProgress(vm) {
+		err = awsSession.RollVM(vm)
+	} else {
+		err = awsSession.ScaleVM(vm)
+	}
+	if err != nil {
+		reason := reasonScaleFailed
+		if aws.SpotCapacityExhausted(vm, err) {
+			reason = reasonSpotCapacity
+			err = fmt.Errorf("spot capacity unavailable and fallbackToOnDemand is disabled: %w", err)
+		}
+		log.Error(err, "failed to scale VM")
+		return r.syncFailed(ctx, vm, reason, err)
+	}
+	if vm.Status.Status != string(delete) {
+		if reason, err := r.syncInstances(ctx, vm, awsSession); err != nil {
+			return r.syncFailed(ctx, vm, reason, err)
+		}
+		vm.Status.Status = string(powerStatus(vm))
+	}
+	err = awsSession.SyncMetadataOptions(vm, instances)
+	if err != nil {
+		log.Error(err, "failed to sync instance metadata options")
+		return r.syncFailed(ctx, vm, reasonMetadataOptionsFailed, err)
+	}
+	err = awsSession.SyncTags(vm, instances)
+	if err != nil {
+		log.Error(err, "failed to sync instance tags")
+		return r.syncFailed(ctx, vm, reasonTagsFailed, err)
+	}
+	err = awsSession.SyncSecurityGroups(vm, instances)
+	if err != nil {
+		log.Error(err, "failed to sync instance security groups")
+		return r.syncFailed(ctx, vm, reasonSecurityGroupsFailed, err)
+	}
+	err = awsSession.SyncSourceDestCheck(vm, instances)
+	if err != nil {
+		log.Error(err, "failed to sync source/dest check")
+		return r.syncFailed(ctx, vm, reasonSourceDestCheckFailed, err)
+	}
+	if vm.Status.Status != string(delete) {
+		// Drift is only detected once a spec change has been applied
+		// in full, which may take several reconciles
+		if aws.SpecApplied(vm) {
+			vm.Status.AppliedGeneration = vm.Generation
+		}
+		updateConditions(vm)
+	}
+	err = r.Status().Update(ctx, vm)
+	if err != nil {
+		log.Error(err, "failed to update CRD status")
+		return ctrl.Result{}, err
+	}
+	// Follow in-place updates closely, otherwise requeue periodically so
+	// drift on the instances is picked up
+	if aws.UpdateInProgress(vm) || aws.RolloutInProgress(vm) {
+		return ctrl.Result{RequeueAfter: updateRequeueInterval}, nil
+	}
+	return ctrl.Result{RequeueAfter: time.Minute}, nil
+}
 
-	return ctrl.Result{}, nil
+// syncInstances updates the instances of a provisioned Vm in place: it
+// resizes them, moves them to the desired power state, repairs unhealthy
+// ones and associates their Elastic IPs. It returns the condition reason of
+// the step that failed.
+func (r *VmReconciler) syncInstances(ctx context.Context, vm *v1.Vm, awsSession *aws.AwsSession) (string, error) {
+	log := log.FromContext(ctx)
+
+	err := awsSession.ResizeVM(vm)
+	if err != nil {
+		log.Error(err, "failed to resize VM")
+		return reasonResizeFailed, err
+	}
+	err = awsSession.SyncPowerState(vm)
+	if err != nil {
+		log.Error(err, "failed to sync instance power state")
+		return reasonPowerStateFailed, err
+	}
+	err = awsSession.SyncHealth(vm)
+	if err != nil {
+		log.Error(err, "failed to check instance health")
+		return reasonHealthCheckFailed, err
+	}
+	remediations, err := awsSession.RemediateHealth(vm)
+	for _, remediation := range remediations {
+		log.Info("Remediating unhealthy instance", "instance", remediation.InstanceId, "action", remediation.Action)
+		r.Recorder.Eventf(vm, corev1.EventTypeNormal, reasonRemediated, "%s of instance %s: %s",
+			remediation.Action, remediation.InstanceId, remediation.Reason)
+	}
+	if err != nil {
+		log.Error(err, "failed to remediate unhealthy instances")
+		return reasonHealthCheckFailed, err
+	}
+	err = awsSession.SyncElasticIps(vm)
+	if err != nil {
+		log.Error(err, "failed to sync Elastic IPs")
+		return reasonElasticIpFailed, err
+	}
+	return "", nil
+}
+
+// removeFinalizer releases the Vm so Kubernetes can delete it.
+func (r *VmReconciler) removeFinalizer(ctx context.Context, vm *v1.Vm) error {
+	if !controllerutil.RemoveFinalizer(vm, controllerFinalizer) {
+		return nil
+	}
+	if err := r.Update(ctx, vm); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to remove finalizer for controller")
+		return err
+	}
+	return nil
+}
+
+// credentialsSecretRef returns the credentials Secret of the Vm, or nil when
+// none is specified.
+func credentialsSecretRef(vm *v1.Vm) *v1.CredentialsSecret {
+	if vm.CredentialsSecretRef.Name == "" {
+		return nil
+	}
+	secretRef := &vm.CredentialsSecretRef
+	secretRef.Namespace = vm.Namespace
+	return secretRef
+}
+
+// awsSession creates the AWS session for the credentials in the Secret.
+func (r *VmReconciler) awsSession(ctx context.Context, secretRef *v1.CredentialsSecret) (*aws.AwsSession, error) {
+	secret, err := aws.GetAWSCredentials(ctx, r.Client, secretRef)
+	if err != nil {
+		return nil, err
+	}
+	awsSession, err := aws.GetSession(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+	awsSession.ClusterName = r.ClusterName
+	return awsSession, nil
+}
+
+// credentialsMissing marks a Vm without a credentials Secret as failed.
+func (r *VmReconciler) credentialsMissing(ctx context.Context, vm *v1.Vm) {
+	vm.Status.Status = string(failed)
+	vm.Status.ObservedGeneration = vm.Generation
+	setCondition(vm, v1.ConditionSynced, metav1.ConditionFalse, reasonCredentialsMissing, "Credentials secret not specified in CRD")
+	setCondition(vm, v1.ConditionReady, metav1.ConditionFalse, reasonCredentialsMissing, "Credentials secret not specified in CRD")
+	r.Status().Update(ctx, vm)
+}
+
+// provisioned reports whether the instances of the Vm have been launched.
+func provisioned(vm *v1.Vm) bool {
+	switch Status(vm.Status.Status) {
+	case running, stopped, pending:
+		return true
+	}
+	return false
+}
+
+// powerStatus summarises the instance states into the Vm status: Running or
+// Stopped once every instance reached the desired power state, Pending while
+// any of them is still transitioning.
+func powerStatus(vm *v1.Vm) Status {
+	switch {
+	case !aws.PowerStateReached(vm):
+		return pending
+	case aws.WantsRunning(vm):
+		return running
+	default:
+		return stopped
+	}
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// SetupWithManager sets up the controller with the Manager. Status updates
+// are filtered out, as every reconcile writes the status and would otherwise
+// trigger the next one right away, defeating the requeue intervals and the
+// retry backoff.
 func (r *VmReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1.Vm{}).
+		For(&v1.Vm{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+			lifecycleChangedPredicate(),
+		))).
 		Complete(r)
 }
+
+// lifecycleChangedPredicate passes updates that mark the Vm for deletion or
+// change its finalizers.
+func lifecycleChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return false
+			}
+			return !e.ObjectOld.GetDeletionTimestamp().Equal(e.ObjectNew.GetDeletionTimestamp()) ||
+				!reflect.DeepEqual(e.ObjectOld.GetFinalizers(), e.ObjectNew.GetFinalizers())
+		},
+	}
+}
+
+// driftMessage describes a drifted instance attribute for the Vm events.
+func driftMessage(drift aws.Drift) string {
+	if drift.Expected == "" && drift.Actual == "" {
+		return fmt.Sprintf("%s of instance %s no longer match the spec", drift.Field, drift.InstanceId)
+	}
+	return fmt.Sprintf("%s of instance %s changed outside the controller: expected %s, found %s",
+		drift.Field, drift.InstanceId, drift.Expected, drift.Actual)
+}